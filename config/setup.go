@@ -2,8 +2,11 @@ package config
 
 import (
 	"log"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +14,46 @@ import (
 var (
 	DatabaseURL    string
 	AllowedOrigins map[string]struct{}
+	// ProxyHeader is the header holding the client IP when running behind a reverse proxy,
+	// e.g. X-Forwarded-For or X-Real-IP.
+	ProxyHeader    string
+	TrashRetention time.Duration
+	AppURL         string
+	MailerDriver   string
+	MailFrom       string
+	MailLogDir     string
+	SMTPHost       string
+	SMTPPort       string
+	SMTPUsername   string
+	SMTPPassword   string
+
+	// WebAuthnRPID is the domain passkeys are bound to. It must be the frontend host or a
+	// registrable suffix of it.
+	WebAuthnRPID    string
+	WebAuthnRPName  string
+	WebAuthnOrigins []string
+
+	// SessionIdleTimeout and SessionRememberIdleTimeout are how long a session stays valid
+	// without requests, for logins without and with "remember me". SessionMaxLifetime caps
+	// every session regardless of activity.
+	SessionIdleTimeout         time.Duration
+	SessionRememberIdleTimeout time.Duration
+	SessionMaxLifetime         time.Duration
+	// SessionRefreshThreshold is how long after the last renewal a session is extended again.
+	SessionRefreshThreshold time.Duration
+
+	// CookieSameSite is the SameSite attribute of the session cookie: Lax, Strict or None.
+	CookieSameSite string
+
+	// OIDCIssuer enables single sign-on with an OpenID Connect provider when set.
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
+
+	// RequireVerifiedEmail blocks sharing and inviting until the user's email is verified.
+	RequireVerifiedEmail bool
 )
 
 func Setup() {
@@ -24,4 +67,83 @@ func Setup() {
 	for _, origin := range strings.Split(os.Getenv("ALLOWED_ORIGINS"), ",") {
 		AllowedOrigins[origin] = struct{}{}
 	}
+
+	ProxyHeader = os.Getenv("PROXY_HEADER")
+
+	TrashRetention = 30 * 24 * time.Hour
+	if days := os.Getenv("TRASH_RETENTION_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			log.Fatalln("TRASH_RETENTION_DAYS must be a positive integer")
+		}
+		TrashRetention = time.Duration(n) * 24 * time.Hour
+	}
+
+	SessionIdleTimeout = durationEnv("SESSION_IDLE_TIMEOUT", 24*time.Hour)
+	SessionRememberIdleTimeout = durationEnv("SESSION_REMEMBER_IDLE_TIMEOUT", 30*24*time.Hour)
+	SessionMaxLifetime = durationEnv("SESSION_MAX_LIFETIME", 90*24*time.Hour)
+	SessionRefreshThreshold = durationEnv("SESSION_REFRESH_THRESHOLD", time.Hour)
+
+	CookieSameSite = "Lax"
+	if sameSite := os.Getenv("COOKIE_SAME_SITE"); sameSite != "" {
+		switch strings.ToLower(sameSite) {
+		case "lax":
+			CookieSameSite = "Lax"
+		case "strict":
+			CookieSameSite = "Strict"
+		case "none":
+			CookieSameSite = "None"
+		default:
+			log.Fatalln("COOKIE_SAME_SITE must be Lax, Strict or None")
+		}
+	}
+
+	AppURL = strings.TrimSuffix(os.Getenv("APP_URL"), "/")
+	MailerDriver = os.Getenv("MAILER")
+	MailFrom = os.Getenv("MAIL_FROM")
+	MailLogDir = os.Getenv("MAIL_LOG_DIR")
+	SMTPHost = os.Getenv("SMTP_HOST")
+	SMTPPort = os.Getenv("SMTP_PORT")
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+
+	RequireVerifiedEmail = os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true"
+
+	OIDCIssuer = os.Getenv("OIDC_ISSUER")
+	OIDCClientID = os.Getenv("OIDC_CLIENT_ID")
+	OIDCClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	OIDCRedirectURL = os.Getenv("OIDC_REDIRECT_URL")
+	OIDCScopes = []string{"openid", "email", "profile"}
+	if scopes := os.Getenv("OIDC_SCOPES"); scopes != "" {
+		OIDCScopes = strings.Fields(scopes)
+	}
+
+	WebAuthnRPID = os.Getenv("WEBAUTHN_RP_ID")
+	if WebAuthnRPID == "" {
+		u, err := url.Parse(AppURL)
+		if err != nil {
+			log.Fatalln("APP_URL must be a valid URL")
+		}
+		WebAuthnRPID = u.Hostname()
+	}
+	WebAuthnRPName = os.Getenv("WEBAUTHN_RP_NAME")
+	if WebAuthnRPName == "" {
+		WebAuthnRPName = "Notez"
+	}
+	WebAuthnOrigins = []string{AppURL}
+	if origins := os.Getenv("WEBAUTHN_ORIGINS"); origins != "" {
+		WebAuthnOrigins = strings.Split(origins, ",")
+	}
+}
+
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Fatalln(name + " must be a positive duration such as 24h")
+	}
+	return d
 }
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS note_revisions (
+  id UUID PRIMARY KEY,
+  note_id UUID NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+  user_id UUID REFERENCES users (id) ON DELETE SET NULL,
+  title TEXT NOT NULL,
+  content TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS note_revisions_note_id_created_at_idx
+  ON note_revisions (note_id, created_at DESC);
+
+INSERT INTO note_revisions (id, note_id, user_id, title, content, created_at)
+SELECT uuid_generate_v4(), id, user_id, title, content, updated_at
+FROM notes
+WHERE NOT EXISTS (SELECT 1 FROM note_revisions nr WHERE nr.note_id = notes.id);
@@ -0,0 +1,6 @@
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('simple', title), 'A') ||
+  setweight(to_tsvector('simple', COALESCE(content, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS notes_search_vector_idx ON notes USING GIN (search_vector);
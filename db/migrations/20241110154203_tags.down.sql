@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notes_tags;
+
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS tags (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  name CITEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, name)
+);
+
+CREATE OR REPLACE TRIGGER tags_updated_at
+  BEFORE UPDATE ON tags
+  FOR EACH ROW
+  EXECUTE PROCEDURE moddatetime (updated_at);
+
+CREATE TABLE IF NOT EXISTS notes_tags (
+  note_id UUID NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+  tag_id UUID NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (note_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS notes_tags_tag_id_idx ON notes_tags (tag_id);
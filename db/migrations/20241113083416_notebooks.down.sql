@@ -0,0 +1,7 @@
+DROP VIEW IF EXISTS note_access;
+
+ALTER TABLE notes DROP COLUMN IF EXISTS notebook_id;
+
+DROP TABLE IF EXISTS notebooks_users;
+
+DROP TABLE IF EXISTS notebooks;
@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS notebooks (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  parent_id UUID REFERENCES notebooks (id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  -- Ids from the root notebook down to this one, itself included.
+  path UUID[] NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notebooks_user_id_idx ON notebooks (user_id);
+
+CREATE INDEX IF NOT EXISTS notebooks_path_idx ON notebooks USING GIN (path);
+
+CREATE OR REPLACE TRIGGER notebooks_updated_at
+  BEFORE UPDATE ON notebooks
+  FOR EACH ROW
+  EXECUTE PROCEDURE moddatetime (updated_at);
+
+CREATE TABLE IF NOT EXISTS notebooks_users (
+  notebook_id UUID NOT NULL REFERENCES notebooks (id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  role note_role NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (notebook_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS notebooks_users_user_id_idx ON notebooks_users (user_id);
+
+ALTER TABLE notes
+  ADD COLUMN IF NOT EXISTS notebook_id UUID REFERENCES notebooks (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS notes_notebook_id_idx ON notes (notebook_id);
+
+-- Effective note membership: direct note members plus members of the note's notebook or any of
+-- its ancestors. A user with several grants gets the strongest role.
+CREATE OR REPLACE VIEW note_access AS
+SELECT
+  a.note_id,
+  a.user_id,
+  (CASE WHEN bool_or(a.role = 'editor') THEN 'editor' ELSE 'viewer' END)::note_role AS role
+FROM (
+  SELECT note_id, user_id, role FROM notes_users
+  UNION ALL
+  SELECT n.id, nbu.user_id, nbu.role
+  FROM notes n
+  JOIN notebooks nb ON n.notebook_id = nb.id
+  JOIN notebooks_users nbu ON nbu.notebook_id = ANY (nb.path)
+) a
+GROUP BY a.note_id, a.user_id;
@@ -0,0 +1,5 @@
+DELETE FROM notes WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS notes_deleted_at_idx;
+
+ALTER TABLE notes DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS note_states (
+  note_id UUID NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  pinned BOOLEAN NOT NULL DEFAULT FALSE,
+  archived BOOLEAN NOT NULL DEFAULT FALSE,
+  favorite BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (note_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS note_states_user_id_idx ON note_states (user_id);
+
+CREATE OR REPLACE TRIGGER note_states_updated_at
+  BEFORE UPDATE ON note_states
+  FOR EACH ROW
+  EXECUTE PROCEDURE moddatetime (updated_at);
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  credential_id BYTEA NOT NULL UNIQUE,
+  public_key BYTEA NOT NULL,
+  sign_count BIGINT NOT NULL DEFAULT 0,
+  aaguid BYTEA,
+  transports TEXT[] NOT NULL DEFAULT '{}',
+  backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+  backed_up BOOLEAN NOT NULL DEFAULT FALSE,
+  name TEXT NOT NULL,
+  last_used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+CREATE OR REPLACE TRIGGER webauthn_credentials_updated_at
+  BEFORE UPDATE ON webauthn_credentials
+  FOR EACH ROW
+  EXECUTE PROCEDURE moddatetime (updated_at);
+
+CREATE TABLE IF NOT EXISTS webauthn_challenges (
+  challenge TEXT PRIMARY KEY,
+  user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+  ceremony TEXT NOT NULL CHECK (ceremony IN ('registration', 'login')),
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
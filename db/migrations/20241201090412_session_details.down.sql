@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS sessions_user_id_idx;
+DROP INDEX IF EXISTS sessions_public_id_idx;
+
+ALTER TABLE sessions
+  DROP COLUMN IF EXISTS last_seen_at,
+  DROP COLUMN IF EXISTS created_at,
+  DROP COLUMN IF EXISTS user_agent,
+  DROP COLUMN IF EXISTS ip_address,
+  DROP COLUMN IF EXISTS public_id;
@@ -0,0 +1,9 @@
+ALTER TABLE sessions
+  ADD COLUMN IF NOT EXISTS public_id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  ADD COLUMN IF NOT EXISTS ip_address TEXT,
+  ADD COLUMN IF NOT EXISTS user_agent TEXT,
+  ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE UNIQUE INDEX IF NOT EXISTS sessions_public_id_idx ON sessions (public_id);
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
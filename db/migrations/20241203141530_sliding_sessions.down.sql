@@ -0,0 +1,5 @@
+ALTER TABLE login_challenges DROP COLUMN IF EXISTS remember;
+
+ALTER TABLE sessions
+  DROP COLUMN IF EXISTS absolute_expires_at,
+  DROP COLUMN IF EXISTS remember;
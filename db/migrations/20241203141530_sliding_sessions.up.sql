@@ -0,0 +1,10 @@
+ALTER TABLE sessions
+  ADD COLUMN IF NOT EXISTS remember BOOLEAN NOT NULL DEFAULT TRUE,
+  ADD COLUMN IF NOT EXISTS absolute_expires_at TIMESTAMPTZ;
+
+UPDATE sessions SET absolute_expires_at = expires_at WHERE absolute_expires_at IS NULL;
+
+ALTER TABLE sessions ALTER COLUMN absolute_expires_at SET NOT NULL;
+
+ALTER TABLE login_challenges
+  ADD COLUMN IF NOT EXISTS remember BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS login_throttles (
+  key TEXT PRIMARY KEY,
+  failures INT NOT NULL DEFAULT 0,
+  locked_until TIMESTAMPTZ,
+  last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS account_unlock_tokens (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  token_hash TEXT NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS account_unlock_tokens_user_id_idx ON account_unlock_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users (id) ON DELETE SET NULL,
+  actor_id UUID REFERENCES users (id) ON DELETE SET NULL,
+  action TEXT NOT NULL,
+  ip_address TEXT,
+  details JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_id_idx ON audit_log (user_id, created_at);
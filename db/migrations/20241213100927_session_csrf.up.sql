@@ -0,0 +1,7 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS csrf_token TEXT;
+
+UPDATE sessions
+SET csrf_token = replace(uuid_generate_v4()::TEXT || uuid_generate_v4()::TEXT, '-', '')
+WHERE csrf_token IS NULL;
+
+ALTER TABLE sessions ALTER COLUMN csrf_token SET NOT NULL;
@@ -0,0 +1,15 @@
+-- Invitations for addresses without an account. They turn into note_invitations rows once the
+-- address belongs to a verified user.
+CREATE TABLE IF NOT EXISTS pending_note_invitations (
+  id UUID PRIMARY KEY,
+  note_id UUID NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+  email CITEXT NOT NULL,
+  inviter_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  role note_role NOT NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (note_id, email)
+);
+
+CREATE INDEX IF NOT EXISTS pending_note_invitations_email_idx ON pending_note_invitations (email);
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS note_invitations_inviter_id_idx;
+
+DROP INDEX IF EXISTS note_invitations_user_id_status_idx;
+
+DELETE FROM note_invitations WHERE status <> 'pending';
+
+ALTER TABLE note_invitations
+  DROP COLUMN IF EXISTS responded_at,
+  DROP COLUMN IF EXISTS expires_at,
+  DROP COLUMN IF EXISTS status;
+
+DROP TYPE IF EXISTS invitation_status;
@@ -0,0 +1,19 @@
+DO $$ BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'invitation_status') THEN
+    CREATE TYPE invitation_status AS ENUM ('pending', 'accepted', 'declined', 'revoked', 'expired');
+  END IF;
+END $$;
+
+-- Answered invitations are kept with their final status instead of being deleted.
+ALTER TABLE note_invitations
+  ADD COLUMN IF NOT EXISTS status invitation_status NOT NULL DEFAULT 'pending',
+  ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS responded_at TIMESTAMPTZ;
+
+UPDATE note_invitations SET expires_at = created_at + INTERVAL '14 days' WHERE expires_at IS NULL;
+
+ALTER TABLE note_invitations ALTER COLUMN expires_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS note_invitations_user_id_status_idx ON note_invitations (user_id, status);
+
+CREATE INDEX IF NOT EXISTS note_invitations_inviter_id_idx ON note_invitations (inviter_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS note_join_links (
+  id UUID PRIMARY KEY,
+  note_id UUID NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+  created_by UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  token_hash TEXT NOT NULL UNIQUE,
+  role note_role NOT NULL,
+  max_uses INT,
+  use_count INT NOT NULL DEFAULT 0,
+  -- Only users whose verified email is at this domain may join when set.
+  allowed_domain CITEXT,
+  expires_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS note_join_links_note_id_idx ON note_join_links (note_id);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS note_publications (
+  note_id UUID PRIMARY KEY REFERENCES notes (id) ON DELETE CASCADE,
+  slug TEXT NOT NULL UNIQUE,
+  -- argon2id hash, readers must enter the password when set.
+  password TEXT,
+  expires_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE TRIGGER note_publications_updated_at
+  BEFORE UPDATE ON note_publications
+  FOR EACH ROW
+  EXECUTE PROCEDURE moddatetime (updated_at);
@@ -0,0 +1,18 @@
+-- Ownership transfers wait for the recipient to accept. Answered ones keep their final status.
+CREATE TABLE IF NOT EXISTS note_transfers (
+  id UUID PRIMARY KEY,
+  note_id UUID NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+  from_user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  to_user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  status invitation_status NOT NULL DEFAULT 'pending',
+  expires_at TIMESTAMPTZ NOT NULL,
+  responded_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS note_transfers_pending_idx ON note_transfers (note_id)
+  WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS note_transfers_to_user_id_status_idx ON note_transfers (to_user_id, status);
+
+CREATE INDEX IF NOT EXISTS note_transfers_from_user_id_idx ON note_transfers (from_user_id);
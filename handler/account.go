@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func ExportProfile(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+
+	export, err := service.GetUserExport(auth.ID)
+	if err != nil {
+		log.Println("Error getting user export:", err)
+		return fiber.ErrInternalServerError
+	}
+	if export == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: userNotFound,
+		})
+	}
+
+	archive, err := buildExportArchive(export)
+	if err != nil {
+		log.Println("Error building export archive:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Attachment(fmt.Sprintf("notez-export-%s.zip", time.Now().UTC().Format("20060102")))
+	return c.Send(archive)
+}
+
+// buildExportArchive writes export.json with all data plus one Markdown file per owned note.
+func buildExportArchive(export *model.UserExport) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+
+	w, err := zw.Create("export.json")
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(export); err != nil {
+		return nil, err
+	}
+
+	for _, n := range export.Notes {
+		dir := "notes"
+		if n.DeletedAt != nil {
+			dir = "trash"
+		}
+		w, err := zw.Create(fmt.Sprintf("%s/%s.md", dir, exportFileName(n.Title, n.ID.String())))
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write([]byte(noteMarkdown(n))); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exportFileName turns the title into a file-safe slug. The id suffix keeps notes with the same
+// title apart.
+func exportFileName(title, id string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= 60 {
+			break
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if slug == "" {
+		return id
+	}
+	return slug + "-" + id
+}
+
+func noteMarkdown(n model.ExportNote) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "id: %s\n", n.ID)
+	if len(n.Tags) > 0 {
+		names := make([]string, len(n.Tags))
+		for i, t := range n.Tags {
+			names[i] = t.Name
+		}
+		fmt.Fprintf(&b, "tags: [%s]\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintf(&b, "created_at: %s\n", n.CreatedAt)
+	fmt.Fprintf(&b, "updated_at: %s\n", n.UpdatedAt)
+	b.WriteString("---\n\n")
+	fmt.Fprintf(&b, "# %s\n", n.Title)
+	if n.Content != nil && *n.Content != "" {
+		b.WriteString("\n")
+		b.WriteString(*n.Content)
+		if !strings.HasSuffix(*n.Content, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func DeleteProfile(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	body := c.Locals("body").(*model.DeleteAccount)
+
+	match, err := checkPassword(auth, body.Password)
+	if err != nil {
+		log.Println("Error comparing password and hash:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !match {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(model.Response{
+			Message: invalidPassword,
+		})
+	}
+
+	transferred, err := service.DeleteAccount(auth.ID, body.SharedNotes == "transfer")
+	if err != nil {
+		log.Println("Error deleting account:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	details := map[string]interface{}{
+		"user_id":           auth.ID,
+		"email":             auth.Email,
+		"transferred_notes": transferred,
+	}
+	if err = service.RecordAudit(nil, nil, "account_deleted", c.IP(), details); err != nil {
+		log.Println("Error recording audit entry:", err)
+	}
+
+	clearSessionCookie(c)
+
+	return c.JSON(model.Response{
+		Message: "Account deleted.",
+	})
+}
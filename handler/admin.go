@@ -0,0 +1,285 @@
+package handler
+
+import (
+	"log"
+	"time"
+
+	"github.com/amiftachulh/notez-api/mailer"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func AdminUnlockUser(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.AdminUserParams)
+
+	user, err := service.GetUserByID(params.ID)
+	if err != nil {
+		log.Println("Error getting user by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: userNotFound,
+		})
+	}
+
+	if err = service.ClearLoginFailures(service.LoginAccountKey(user.Email)); err != nil {
+		log.Println("Error clearing login failures:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	if err = service.RecordAudit(&user.ID, &auth.ID, "login_unlocked", c.IP(), nil); err != nil {
+		log.Println("Error recording audit entry:", err)
+	}
+
+	return c.JSON(model.Response{
+		Message: "Account unlocked.",
+	})
+}
+
+func AdminGetUsers(c *fiber.Ctx) error {
+	query := c.Locals("query").(*model.AdminUserQuery)
+
+	users, total, err := service.GetAdminUsers(query)
+	if err != nil {
+		log.Println("Error getting users:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(model.PaginationResponse{
+		Total: total,
+		Items: users,
+	})
+}
+
+func AdminGetUser(c *fiber.Ctx) error {
+	params := c.Locals("params").(*model.AdminUserParams)
+
+	user, err := service.GetAdminUserByID(params.ID)
+	if err != nil {
+		log.Println("Error getting user by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: userNotFound,
+		})
+	}
+
+	return c.JSON(user)
+}
+
+func AdminDisableUser(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.AdminUserParams)
+	body := c.Locals("body").(*model.AdminDisableUser)
+
+	if params.ID == auth.ID {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: cannotModifySelf,
+		})
+	}
+
+	if body.TransferTo != nil {
+		if *body.TransferTo == params.ID {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(model.Response{
+				Message: "Notes can't be transferred to the user being disabled.",
+			})
+		}
+		recipient, err := service.GetAdminUserByID(*body.TransferTo)
+		if err != nil {
+			log.Println("Error getting user by ID:", err)
+			return fiber.ErrInternalServerError
+		}
+		if recipient == nil || recipient.DisabledAt != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(model.Response{
+				Message: "Transfer recipient must be an active user.",
+			})
+		}
+	}
+
+	transfer := body.TransferNotes || body.TransferTo != nil
+	result, transfers, err := service.DisableUser(
+		params.ID,
+		transfer,
+		body.TransferTo,
+		time.Now().Add(noteTransferTTL),
+	)
+	if err != nil {
+		log.Println("Error disabling user:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: userNotFound,
+		})
+	}
+
+	var details map[string]interface{}
+	if transfer {
+		details = map[string]interface{}{"note_transfers": transfers}
+	}
+
+	if err = service.RecordAudit(&params.ID, &auth.ID, "user_disabled", c.IP(), details); err != nil {
+		log.Println("Error recording audit entry:", err)
+	}
+
+	return c.JSON(model.Response{
+		Message: "Account disabled.",
+	})
+}
+
+func AdminEnableUser(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.AdminUserParams)
+
+	if params.ID == auth.ID {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: cannotModifySelf,
+		})
+	}
+
+	result, err := service.SetUserDisabled(params.ID, false)
+	if err != nil {
+		log.Println("Error updating user status:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: userNotFound,
+		})
+	}
+
+	if err = service.RecordAudit(&params.ID, &auth.ID, "user_enabled", c.IP(), nil); err != nil {
+		log.Println("Error recording audit entry:", err)
+	}
+
+	return c.JSON(model.Response{
+		Message: "Account enabled.",
+	})
+}
+
+func AdminForcePasswordReset(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.AdminUserParams)
+
+	if params.ID == auth.ID {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: cannotModifySelf,
+		})
+	}
+
+	user, err := service.GetUserByID(params.ID)
+	if err != nil {
+		log.Println("Error getting user by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: userNotFound,
+		})
+	}
+
+	if _, err = service.RequirePasswordReset(user.ID); err != nil {
+		log.Println("Error requiring password reset:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	token, err := service.CreatePasswordResetToken(user.ID, time.Now().Add(passwordResetTTL))
+	if err != nil {
+		log.Println("Error creating password reset token:", err)
+		return fiber.ErrInternalServerError
+	}
+	mailer.SendAsync(mailer.PasswordResetMessage(user.Email, token, formatTTL(passwordResetTTL)))
+
+	if err = service.RecordAudit(&user.ID, &auth.ID, "password_reset_forced", c.IP(), nil); err != nil {
+		log.Println("Error recording audit entry:", err)
+	}
+
+	return c.JSON(model.Response{
+		Message: "Password reset required. A reset link has been sent to the user.",
+	})
+}
+
+func AdminUpdateUserRole(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.AdminUserParams)
+	body := c.Locals("body").(*model.UpdateUserRole)
+
+	// Admins can't demote themselves, so there is always at least one admin left.
+	if params.ID == auth.ID {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: cannotModifySelf,
+		})
+	}
+
+	result, err := service.UpdateUserRole(params.ID, body.Role)
+	if err != nil {
+		log.Println("Error updating user role:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: userNotFound,
+		})
+	}
+
+	details := map[string]interface{}{"role": body.Role}
+	if err = service.RecordAudit(&params.ID, &auth.ID, "role_updated", c.IP(), details); err != nil {
+		log.Println("Error recording audit entry:", err)
+	}
+
+	return c.JSON(model.Response{
+		Message: "User role updated.",
+	})
+}
+
+func AdminDeleteUser(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.AdminUserParams)
+
+	if params.ID == auth.ID {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: cannotModifySelf,
+		})
+	}
+
+	user, err := service.GetUserByID(params.ID)
+	if err != nil {
+		log.Println("Error getting user by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: userNotFound,
+		})
+	}
+
+	deleted, transferred, err := service.DeleteUser(user.ID)
+	if err != nil {
+		log.Println("Error deleting user:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: userNotFound,
+		})
+	}
+
+	// The audit row outlives the user with user_id set to NULL, so keep the email for reference.
+	details := map[string]interface{}{
+		"user_id":           user.ID,
+		"email":             user.Email,
+		"transferred_notes": transferred,
+	}
+	if err = service.RecordAudit(nil, &auth.ID, "user_deleted", c.IP(), details); err != nil {
+		log.Println("Error recording audit entry:", err)
+	}
+
+	return c.JSON(model.Response{
+		Message: "User deleted.",
+	})
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"log"
+
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func CreateAPIToken(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	body := c.Locals("body").(*model.APITokenInput)
+
+	token, err := service.CreateAPIToken(auth.ID, body)
+	if err != nil {
+		log.Println("Error creating API token:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(token)
+}
+
+func GetAPITokens(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+
+	tokens, err := service.GetAPITokens(auth.ID)
+	if err != nil {
+		log.Println("Error getting API tokens:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(tokens)
+}
+
+func RevokeAPIToken(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.APITokenParams)
+
+	result, err := service.RevokeAPIToken(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error revoking API token:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: apiTokenNotFound,
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Token revoked.",
+	})
+}
@@ -4,13 +4,19 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"log"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/amiftachulh/notez-api/config"
+	"github.com/amiftachulh/notez-api/mailer"
 	"github.com/amiftachulh/notez-api/model"
 	"github.com/amiftachulh/notez-api/service"
 
 	"github.com/alexedwards/argon2id"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 func Register(c *fiber.Ctx) error {
@@ -27,18 +33,47 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
+	var invitation *model.PendingNoteInvitation
+	if body.InvitationToken != "" {
+		invitation, err = service.GetPendingNoteInvitationByToken(body.InvitationToken)
+		if err != nil {
+			log.Println("Error getting pending note invitation:", err)
+			return fiber.ErrInternalServerError
+		}
+		if invitation == nil || !strings.EqualFold(invitation.Email, body.Email) {
+			return c.Status(fiber.StatusBadRequest).JSON(model.Response{
+				Message: invalidInvitationToken,
+			})
+		}
+	}
+
 	hash, err := hashPassword(body.Password)
 	if err != nil {
 		log.Println("Error creating hash:", err)
 		return fiber.ErrInternalServerError
 	}
 
-	err = service.CreateUser(body.Email, hash)
+	userID, err := service.CreateUser(body.Email, hash)
 	if err != nil {
 		log.Println("Error registering user:", err)
 		return fiber.ErrInternalServerError
 	}
 
+	if invitation != nil {
+		if err = service.AcceptPendingNoteInvitation(userID, body.Email, invitation.ID); err != nil {
+			log.Println("Error accepting pending note invitation:", err)
+			return fiber.ErrInternalServerError
+		}
+		return c.Status(fiber.StatusCreated).JSON(model.Response{
+			Message: "Register success.",
+		})
+	}
+
+	if err = sendEmailVerification(userID, body.Email); err != nil {
+		log.Println("Error creating email verification token:", err)
+		return fiber.ErrInternalServerError
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(model.Response{
 		Message: "Register success.",
 	})
@@ -47,48 +82,74 @@ func Register(c *fiber.Ctx) error {
 func Login(c *fiber.Ctx) error {
 	body := c.Locals("body").(*model.Login)
 
-	user, err := service.GetUserByEmail(body.Email)
+	retryAfter, err := service.GetLoginRetryAfter(
+		service.LoginAccountKey(body.Email),
+		service.LoginIPKey(c.IP()),
+	)
 	if err != nil {
-		log.Println("Error getting user by email:", err)
+		log.Println("Error checking login throttle:", err)
 		return fiber.ErrInternalServerError
 	}
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(model.Response{
-			Message: invalidEmailPassword,
-		})
+	if retryAfter > 0 {
+		return tooManyLoginAttempts(c, retryAfter)
 	}
 
-	match, err := argon2id.ComparePasswordAndHash(body.Password, user.Password)
+	user, err := service.GetUserByEmail(body.Email)
 	if err != nil {
-		log.Println("Error comparing password and hash:", err)
+		log.Println("Error getting user by email:", err)
 		return fiber.ErrInternalServerError
 	}
+
+	match := false
+	if user != nil {
+		match, err = argon2id.ComparePasswordAndHash(body.Password, user.Password)
+		if err != nil {
+			log.Println("Error comparing password and hash:", err)
+			return fiber.ErrInternalServerError
+		}
+	}
 	if !match {
+		// Unknown emails are counted like known ones so lockouts don't reveal which exist.
+		if err = recordLoginFailure(c, body.Email, user); err != nil {
+			log.Println("Error recording login failure:", err)
+			return fiber.ErrInternalServerError
+		}
 		return c.Status(fiber.StatusUnauthorized).JSON(model.Response{
 			Message: invalidEmailPassword,
 		})
 	}
 
-	bytes := make([]byte, 15)
-	rand.Read(bytes)
-	sessionID := base64.RawURLEncoding.EncodeToString(bytes)
-	expiresAt := time.Now().Add(7 * 24 * time.Hour).Truncate(time.Microsecond)
-	user.ExpiresAt = expiresAt
+	if user.Disabled {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: accountDisabled,
+		})
+	}
+	if user.PasswordResetRequired {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: passwordResetRequired,
+		})
+	}
 
-	if err = service.CreateSession(sessionID, user.ID, expiresAt); err != nil {
-		log.Println("Error creating session:", err)
-		return fiber.ErrInternalServerError
+	if user.TwoFactorEnabled {
+		challenge, err := service.CreateLoginChallenge(user.ID, body.RememberMe, time.Now().Add(loginChallengeTTL))
+		if err != nil {
+			log.Println("Error creating login challenge:", err)
+			return fiber.ErrInternalServerError
+		}
+		return c.JSON(model.LoginChallenge{
+			TwoFactorRequired: true,
+			Challenge:         challenge,
+			ExpiresIn:         int(loginChallengeTTL.Seconds()),
+		})
 	}
 
-	cookie := new(fiber.Cookie)
-	cookie.Name = "session"
-	cookie.Value = sessionID
-	cookie.Expires = expiresAt
-	cookie.HTTPOnly = true
-	cookie.Secure = true
-	c.Cookie(cookie)
+	// With two-factor enabled the failures are only cleared once the second factor passes.
+	if err = service.ClearLoginFailures(service.LoginAccountKey(body.Email)); err != nil {
+		log.Println("Error clearing login failures:", err)
+		return fiber.ErrInternalServerError
+	}
 
-	return c.JSON(user)
+	return startSession(c, user, body.RememberMe)
 }
 
 func Logout(c *fiber.Ctx) error {
@@ -106,13 +167,7 @@ func Logout(c *fiber.Ctx) error {
 		return fiber.ErrUnauthorized
 	}
 
-	cookie := new(fiber.Cookie)
-	cookie.Name = "session"
-	cookie.Value = ""
-	cookie.Expires = time.Now()
-	cookie.HTTPOnly = true
-	cookie.Secure = true
-	c.Cookie(cookie)
+	clearSessionCookie(c)
 
 	return c.JSON(model.Response{
 		Message: "Logout success.",
@@ -133,3 +188,200 @@ func CheckAuth(c *fiber.Ctx) error {
 
 	return c.JSON(user)
 }
+
+func ForgotPassword(c *fiber.Ctx) error {
+	body := c.Locals("body").(*model.ForgotPassword)
+
+	user, err := service.GetUserByEmail(body.Email)
+	if err != nil {
+		log.Println("Error getting user by email:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	if user != nil {
+		expiresAt := time.Now().Add(passwordResetTTL)
+		token, err := service.CreatePasswordResetToken(user.ID, expiresAt)
+		if err != nil {
+			log.Println("Error creating password reset token:", err)
+			return fiber.ErrInternalServerError
+		}
+		mailer.SendAsync(mailer.PasswordResetMessage(user.Email, token, formatTTL(passwordResetTTL)))
+	}
+
+	return c.JSON(model.Response{
+		Message: "If the email is registered, a password reset link has been sent.",
+	})
+}
+
+func ResetPassword(c *fiber.Ctx) error {
+	body := c.Locals("body").(*model.ResetPassword)
+
+	hash, err := hashPassword(body.Password)
+	if err != nil {
+		log.Println("Error creating hash:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	result, err := service.ResetPassword(body.Token, hash)
+	if err != nil {
+		log.Println("Error resetting password:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusBadRequest).JSON(model.Response{
+			Message: "Reset token is invalid or has expired.",
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Password has been reset.",
+	})
+}
+
+func VerifyEmail(c *fiber.Ctx) error {
+	body := c.Locals("body").(*model.VerifyEmail)
+
+	verification, err := service.GetEmailVerification(body.Token)
+	if err != nil {
+		log.Println("Error getting email verification:", err)
+		return fiber.ErrInternalServerError
+	}
+	if verification == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.Response{
+			Message: invalidVerificationToken,
+		})
+	}
+
+	user, err := service.GetUserByEmail(verification.Email)
+	if err != nil {
+		log.Println("Error getting user by email:", err)
+		return fiber.ErrInternalServerError
+	}
+	if user != nil && user.ID != verification.UserID {
+		return c.Status(fiber.StatusConflict).JSON(model.Response{
+			Message: emailUsed,
+		})
+	}
+
+	result, err := service.ConfirmEmail(body.Token, verification)
+	if err != nil {
+		log.Println("Error confirming email:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusBadRequest).JSON(model.Response{
+			Message: invalidVerificationToken,
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Email verified.",
+	})
+}
+
+func UnlockAccount(c *fiber.Ctx) error {
+	body := c.Locals("body").(*model.UnlockAccount)
+
+	userID, err := service.UnlockAccount(body.Token)
+	if err != nil {
+		log.Println("Error unlocking account:", err)
+		return fiber.ErrInternalServerError
+	}
+	if userID == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.Response{
+			Message: "Unlock token is invalid or has expired.",
+		})
+	}
+
+	if err = service.RecordAudit(userID, userID, "login_unlocked", c.IP(), nil); err != nil {
+		log.Println("Error recording audit entry:", err)
+	}
+
+	return c.JSON(model.Response{
+		Message: "Account unlocked.",
+	})
+}
+
+// recordLoginFailure counts the failure for the account and the client IP. When the account
+// gets locked, the lockout is audited and the owner is emailed a link to unlock it.
+func recordLoginFailure(c *fiber.Ctx, email string, user *model.AuthUser) error {
+	if _, err := service.RecordIPLoginFailure(c.IP()); err != nil {
+		return err
+	}
+	lockout, err := service.RecordAccountLoginFailure(email)
+	if err != nil {
+		return err
+	}
+	if lockout == 0 || user == nil {
+		return nil
+	}
+
+	details := map[string]interface{}{"locked_for_seconds": int(lockout.Seconds())}
+	if err = service.RecordAudit(&user.ID, nil, "login_locked", c.IP(), details); err != nil {
+		return err
+	}
+
+	token, err := service.CreateAccountUnlockToken(user.ID, time.Now().Add(accountUnlockTTL))
+	if err != nil {
+		return err
+	}
+	mailer.SendAsync(mailer.AccountLockedMessage(user.Email, token, lockout.String()))
+	return nil
+}
+
+func tooManyLoginAttempts(c *fiber.Ctx, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return c.Status(fiber.StatusTooManyRequests).JSON(model.Response{
+		Message: "Too many failed login attempts. Try again later.",
+	})
+}
+
+// startSession creates a session for the authenticated user, sets the session cookie and
+// responds with the user.
+func startSession(c *fiber.Ctx, user *model.AuthUser, remember bool) error {
+	if err := setSession(c, user, remember); err != nil {
+		log.Println("Error creating session:", err)
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(user)
+}
+
+// setSession creates a session and sets the session cookie. Sessions without remember get a
+// browser session cookie.
+func setSession(c *fiber.Ctx, user *model.AuthUser, remember bool) error {
+	bytes := make([]byte, 15)
+	rand.Read(bytes)
+	sessionID := base64.RawURLEncoding.EncodeToString(bytes)
+
+	expiresAt, csrfToken, err := service.CreateSession(sessionID, user.ID, remember, c.IP(), c.Get(fiber.HeaderUserAgent))
+	if err != nil {
+		return err
+	}
+	user.ExpiresAt = expiresAt
+	user.CSRFToken = csrfToken
+
+	cookie := new(fiber.Cookie)
+	cookie.Name = "session"
+	cookie.Value = sessionID
+	if remember {
+		cookie.Expires = expiresAt
+	} else {
+		cookie.SessionOnly = true
+	}
+	cookie.HTTPOnly = true
+	cookie.Secure = true
+	cookie.SameSite = config.CookieSameSite
+	c.Cookie(cookie)
+	return nil
+}
+
+func sendEmailVerification(userID uuid.UUID, email string) error {
+	expiresAt := time.Now().Add(emailVerificationTTL)
+	token, err := service.CreateEmailVerificationToken(userID, email, expiresAt)
+	if err != nil {
+		return err
+	}
+	mailer.SendAsync(mailer.EmailVerificationMessage(email, token, formatTTL(emailVerificationTTL)))
+	return nil
+}
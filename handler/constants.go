@@ -1,5 +1,21 @@
 package handler
 
+import "time"
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 24 * time.Hour
+	loginChallengeTTL    = 5 * time.Minute
+	webauthnChallengeTTL = 5 * time.Minute
+	oidcStateTTL         = 10 * time.Minute
+	accountUnlockTTL     = 24 * time.Hour
+	noteInvitationTTL    = 14 * 24 * time.Hour
+	pendingInvitationTTL = 7 * 24 * time.Hour
+	noteTransferTTL      = 14 * 24 * time.Hour
+)
+
+const totpIssuer = "Notez"
+
 const (
 	invalidJSON          = "Malformed JSON."
 	validationErr        = "Validation failed."
@@ -8,4 +24,26 @@ const (
 	userNotFound         = "User not found."
 	emailUsed            = "Email is already used."
 	updatePasswordFail   = "Failed to update user password."
+	noteNotFound         = "Note not found."
+	revisionNotFound     = "Revision not found."
+	tagNotFound          = "Tag not found."
+	tagNameUsed          = "Tag name is already used."
+	notebookNotFound     = "Notebook not found."
+	passkeyNotFound      = "Passkey not found."
+	sessionNotFound      = "Session not found."
+	apiTokenNotFound     = "Token not found."
+	joinLinkNotFound     = "Join link not found."
+	noteTransferNotFound = "Transfer not found."
+
+	invalidVerificationToken = "Verification token is invalid or has expired."
+	invalidTwoFactorCode     = "Invalid two-factor code."
+	invalidPassword          = "Password is incorrect."
+	invalidWebAuthnChallenge = "Passkey challenge is invalid or has expired."
+	passkeyVerificationFail  = "Passkey verification failed."
+	ssoNotConfigured         = "Single sign-on is not configured."
+	accountDisabled          = "This account has been disabled."
+	passwordResetRequired    = "A password reset is required. Check your email for a reset link."
+	cannotModifySelf         = "You cannot perform this action on your own account."
+	invalidInvitationToken   = "Invitation is invalid or has expired."
+	invitationNotFound       = "Invitation not found."
 )
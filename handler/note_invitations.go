@@ -3,7 +3,11 @@ package handler
 import (
 	"fmt"
 	"log"
+	"math"
+	"strconv"
+	"time"
 
+	"github.com/amiftachulh/notez-api/mailer"
 	"github.com/amiftachulh/notez-api/model"
 	"github.com/amiftachulh/notez-api/service"
 
@@ -48,9 +52,7 @@ func CreateNoteInvitation(c *fiber.Ctx) error {
 		return fiber.ErrInternalServerError
 	}
 	if targetUserID == nil {
-		return c.Status(fiber.StatusNotFound).JSON(model.Response{
-			Message: fmt.Sprintf("User with email '%s' is not found.", body.Email),
-		})
+		return createPendingNoteInvitation(c, auth, body)
 	}
 
 	inviteExists, err := service.CheckInviteExists(body.NoteID, *targetUserID)
@@ -64,7 +66,13 @@ func CreateNoteInvitation(c *fiber.Ctx) error {
 		})
 	}
 
-	err = service.CreateNoteInvitation(body.NoteID, *targetUserID, auth.ID, body.Role)
+	err = service.CreateNoteInvitation(
+		body.NoteID,
+		*targetUserID,
+		auth.ID,
+		body.Role,
+		time.Now().Add(noteInvitationTTL),
+	)
 	if err != nil {
 		log.Println("Error creating note invitation:", err)
 		return fiber.ErrInternalServerError
@@ -99,9 +107,28 @@ func RespondNoteInvitation(c *fiber.Ctx) error {
 		})
 	}
 
+	noteInvitation, err := service.GetNoteInvitationByID(id, auth.ID)
+	if err != nil {
+		log.Println("Error getting note invitation by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if noteInvitation == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: invitationNotFound,
+		})
+	}
+	if !noteInvitation.ExpiresAt.After(time.Now()) {
+		if err = service.ExpireInvitation(noteInvitation.ID); err != nil {
+			log.Println("Error expiring invitation:", err)
+			return fiber.ErrInternalServerError
+		}
+		return c.Status(fiber.StatusGone).JSON(model.Response{
+			Message: "Invitation has expired.",
+		})
+	}
+
 	if !body.Accept {
-		err := service.DeclineInvitation(id, auth.ID)
-		if err != nil {
+		if _, err = service.DeclineInvitation(noteInvitation.ID, auth.ID); err != nil {
 			log.Println("Error declining invitation:", err)
 			return fiber.ErrInternalServerError
 		}
@@ -110,24 +137,195 @@ func RespondNoteInvitation(c *fiber.Ctx) error {
 		})
 	}
 
-	noteInvitation, err := service.GetNoteInvitationByID(id, auth.ID)
+	result, err := service.AcceptInvitation(
+		noteInvitation.ID,
+		noteInvitation.NoteID,
+		auth.ID,
+		noteInvitation.Role,
+	)
 	if err != nil {
-		log.Println("Error getting note invitation by ID:", err)
+		log.Println("Error accepting invitation:", err)
 		return fiber.ErrInternalServerError
 	}
-	if noteInvitation == nil {
+	if !result {
+		// The invitation was answered, revoked or expired since it was looked up.
+		if !noteInvitation.ExpiresAt.After(time.Now()) {
+			return c.Status(fiber.StatusGone).JSON(model.Response{
+				Message: "Invitation has expired.",
+			})
+		}
 		return c.Status(fiber.StatusNotFound).JSON(model.Response{
-			Message: "Invitation not found.",
+			Message: invitationNotFound,
 		})
 	}
 
-	err = service.AcceptInvitation(noteInvitation.NoteID, auth.ID, noteInvitation.Role)
+	return c.JSON(model.Response{
+		Message: "Invitation accepted.",
+	})
+}
+
+func GetSentNoteInvitations(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	invitations, err := service.GetSentNoteInvitations(auth.ID)
 	if err != nil {
-		log.Println("Error accepting invitation:", err)
+		log.Println("Error getting sent note invitations:", err)
 		return fiber.ErrInternalServerError
 	}
+	return c.JSON(invitations)
+}
+
+func RevokeNoteInvitation(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NoteInvitationParams).ID
+
+	result, err := service.RevokeInvitation(id, auth.ID)
+	if err != nil {
+		log.Println("Error revoking invitation:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: invitationNotFound,
+		})
+	}
 
 	return c.JSON(model.Response{
-		Message: "Invitation accepted.",
+		Message: "Invitation revoked.",
+	})
+}
+
+// createPendingNoteInvitation emails an invitation to an address without an account. It is
+// delivered as a regular invitation once someone registers and verifies the address.
+func createPendingNoteInvitation(
+	c *fiber.Ctx,
+	auth model.AuthUser,
+	body *model.CreateNoteInvitation,
+) error {
+	exists, err := service.CheckPendingInviteExists(body.NoteID, body.Email)
+	if err != nil {
+		log.Println("Error checking pending invite exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if exists {
+		return c.Status(fiber.StatusConflict).JSON(model.Response{
+			Message: "Email already invited to note.",
+		})
+	}
+
+	retryAfter, err := service.GetLoginRetryAfter(service.InvitationEmailKey(auth.ID))
+	if err != nil {
+		log.Println("Error checking invitation throttle:", err)
+		return fiber.ErrInternalServerError
+	}
+	if retryAfter > 0 {
+		return tooManyInvitations(c, retryAfter)
+	}
+
+	token, title, err := service.CreatePendingNoteInvitation(
+		body.NoteID,
+		body.Email,
+		auth.ID,
+		body.Role,
+		time.Now().Add(pendingInvitationTTL),
+	)
+	if err != nil {
+		log.Println("Error creating pending note invitation:", err)
+		return fiber.ErrInternalServerError
+	}
+	if err = service.RecordInvitationEmail(auth.ID); err != nil {
+		log.Println("Error recording invitation email:", err)
+		return fiber.ErrInternalServerError
+	}
+	sendNoteInvitationEmail(auth, body.Email, title, token)
+
+	return c.Status(fiber.StatusCreated).JSON(model.Response{
+		Message: fmt.Sprintf("Invitation sent to '%s'.", body.Email),
+	})
+}
+
+func GetPendingNoteInvitations(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	invitations, err := service.GetPendingNoteInvitations(auth.ID)
+	if err != nil {
+		log.Println("Error getting pending note invitations:", err)
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(invitations)
+}
+
+func ResendPendingNoteInvitation(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NoteInvitationParams).ID
+
+	retryAfter, err := service.GetLoginRetryAfter(
+		service.InvitationEmailKey(auth.ID),
+		service.InvitationResendKey(id),
+	)
+	if err != nil {
+		log.Println("Error checking invitation throttle:", err)
+		return fiber.ErrInternalServerError
+	}
+	if retryAfter > 0 {
+		return tooManyInvitations(c, retryAfter)
+	}
+
+	invitation, token, title, err := service.RenewPendingNoteInvitation(
+		id,
+		auth.ID,
+		time.Now().Add(pendingInvitationTTL),
+	)
+	if err != nil {
+		log.Println("Error renewing pending note invitation:", err)
+		return fiber.ErrInternalServerError
+	}
+	if invitation == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: invitationNotFound,
+		})
+	}
+	if err = service.RecordInvitationResend(auth.ID, invitation.ID); err != nil {
+		log.Println("Error recording invitation resend:", err)
+		return fiber.ErrInternalServerError
+	}
+	sendNoteInvitationEmail(auth, invitation.Email, title, token)
+
+	return c.JSON(model.Response{
+		Message: "Invitation resent.",
+	})
+}
+
+func DeletePendingNoteInvitation(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NoteInvitationParams).ID
+
+	result, err := service.DeletePendingNoteInvitation(id, auth.ID)
+	if err != nil {
+		log.Println("Error deleting pending note invitation:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: invitationNotFound,
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Invitation revoked.",
+	})
+}
+
+func sendNoteInvitationEmail(inviter model.AuthUser, email, noteTitle, token string) {
+	name := inviter.Email
+	if inviter.Name != nil {
+		name = *inviter.Name
+	}
+	mailer.SendAsync(mailer.NoteInvitationMessage(email, name, noteTitle, token, formatTTL(pendingInvitationTTL)))
+}
+
+func tooManyInvitations(c *fiber.Ctx, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return c.Status(fiber.StatusTooManyRequests).JSON(model.Response{
+		Message: "Too many invitations sent. Try again later.",
 	})
 }
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/amiftachulh/notez-api/config"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func CreateNoteJoinLink(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NoteParams)
+	body := c.Locals("body").(*model.NoteJoinLinkInput)
+
+	noteExists, err := service.CheckNoteExists(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error checking note exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !noteExists {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	link, err := service.CreateNoteJoinLink(params.ID, auth.ID, body)
+	if err != nil {
+		log.Println("Error creating note join link:", err)
+		return fiber.ErrInternalServerError
+	}
+	link.URL = fmt.Sprintf("%s/join/%s", config.AppURL, link.Token)
+
+	return c.Status(fiber.StatusCreated).JSON(link)
+}
+
+func GetNoteJoinLinks(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NoteParams)
+
+	noteExists, err := service.CheckNoteExists(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error checking note exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !noteExists {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	links, err := service.GetNoteJoinLinks(params.ID)
+	if err != nil {
+		log.Println("Error getting note join links:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(links)
+}
+
+func RevokeNoteJoinLink(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NoteJoinLinkParams)
+
+	noteExists, err := service.CheckNoteExists(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error checking note exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !noteExists {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	result, err := service.RevokeNoteJoinLink(params.LinkID, params.ID)
+	if err != nil {
+		log.Println("Error revoking note join link:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: joinLinkNotFound,
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Join link revoked.",
+	})
+}
+
+func JoinNote(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.JoinParams)
+
+	link, err := service.GetNoteJoinLinkByToken(params.Token)
+	if err != nil {
+		log.Println("Error getting note join link:", err)
+		return fiber.ErrInternalServerError
+	}
+	if link == nil || link.RevokedAt != nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: joinLinkNotFound,
+		})
+	}
+	if link.ExpiresAt != nil && !link.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusGone).JSON(model.Response{
+			Message: "Join link has expired.",
+		})
+	}
+	if link.MaxUses != nil && link.UseCount >= *link.MaxUses {
+		return c.Status(fiber.StatusGone).JSON(model.Response{
+			Message: "Join link has reached its usage limit.",
+		})
+	}
+
+	if link.AllowedDomain != nil {
+		// The domain only means something for an address the user has proven to own.
+		if auth.EmailVerifiedAt == nil {
+			return c.Status(fiber.StatusForbidden).JSON(model.Response{
+				Message: "Verify your email before using this join link.",
+			})
+		}
+		domain := auth.Email[strings.LastIndex(auth.Email, "@")+1:]
+		if !strings.EqualFold(domain, *link.AllowedDomain) {
+			return c.Status(fiber.StatusForbidden).JSON(model.Response{
+				Message: fmt.Sprintf("This join link is limited to '%s' email addresses.", *link.AllowedDomain),
+			})
+		}
+	}
+
+	role, err := service.GetNoteRole(link.NoteID, auth.ID)
+	if err != nil {
+		log.Println("Error getting note role:", err)
+		return fiber.ErrInternalServerError
+	}
+	if role != nil {
+		return c.Status(fiber.StatusConflict).JSON(model.Response{
+			Message: "You already have access to this note.",
+		})
+	}
+
+	result, err := service.JoinNoteByLink(link, auth.ID)
+	if err != nil {
+		log.Println("Error joining note by link:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusGone).JSON(model.Response{
+			Message: "Join link is no longer valid.",
+		})
+	}
+
+	return c.JSON(model.JoinNoteResponse{
+		Message: "Joined note.",
+		NoteID:  link.NoteID,
+		Role:    link.Role,
+	})
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/amiftachulh/notez-api/config"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	fastws "github.com/fasthttp/websocket"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// liveMessageLimit bounds messages read from live note clients. An operation replacing the whole
+// note needs a little more than the content limit, plus room for escaping.
+const liveMessageLimit = 2 * model.NOTE_MAX_CONTENT_BYTES
+
+// UpgradeNoteLive checks access to the note before the WebSocket handshake. Browsers don't apply
+// CORS to WebSockets, so the origin is checked here against the allowed origins.
+func UpgradeNoteLive(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	if origin := c.Get(fiber.HeaderOrigin); origin != "" {
+		if _, ok := config.AllowedOrigins[origin]; !ok {
+			return fiber.ErrForbidden
+		}
+	}
+
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NoteParams).ID
+
+	role, err := service.GetNoteRole(id, auth.ID)
+	if err != nil {
+		log.Println("Error getting note role:", err)
+		return fiber.ErrInternalServerError
+	}
+	if role == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	c.Locals("role", *role)
+	return c.Next()
+}
+
+var NoteLive = websocket.New(func(conn *websocket.Conn) {
+	auth := conn.Locals("auth").(model.AuthUser)
+	id := conn.Locals("params").(*model.NoteParams).ID
+	role := conn.Locals("role").(string)
+
+	client := service.NewLiveClient(auth.ID, role)
+	if err := service.JoinLiveNote(id, client); err != nil {
+		log.Println("Error joining live note:", err)
+		conn.WriteJSON(model.LiveMessage{Type: "error", Message: "Failed to open note."})
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range client.Send {
+			if err := conn.WriteJSON(msg); err != nil {
+				break
+			}
+		}
+		conn.Close()
+	}()
+	go service.WatchLiveAccess(id, client, done)
+
+	conn.SetReadLimit(liveMessageLimit)
+	readNoteLive(conn, id, client)
+
+	service.LeaveLiveNote(id, client)
+	<-done
+})
+
+// readNoteLive handles messages from the client until the connection closes. A panic while
+// handling a message only drops this connection, so the client still leaves the note.
+func readNoteLive(conn *websocket.Conn, id uuid.UUID, client *service.LiveClient) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error handling live note message:", r)
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if errors.Is(err, fastws.ErrReadLimit) {
+			service.SendLiveError(id, client, "Message is too large.")
+			break
+		}
+		if err != nil {
+			break
+		}
+
+		var msg model.LiveMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			service.SendLiveError(id, client, "Malformed message.")
+			continue
+		}
+
+		switch msg.Type {
+		case "op":
+			if msg.Ops == nil {
+				service.SendLiveError(id, client, "Operation is required.")
+				continue
+			}
+			service.SubmitLiveOperation(id, client, msg.Revision, msg.Ops)
+		default:
+			service.SendLiveError(id, client, "Unknown message type.")
+		}
+	}
+}
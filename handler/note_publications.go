@@ -0,0 +1,307 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/amiftachulh/notez-api/config"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func PublishNote(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NoteParams)
+	body := c.Locals("body").(*model.PublishNote)
+
+	noteExists, err := service.CheckNoteExists(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error checking note exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !noteExists {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	var passwordHash *string
+	if body.Password != nil {
+		hash, err := hashPassword(*body.Password)
+		if err != nil {
+			log.Println("Error creating hash:", err)
+			return fiber.ErrInternalServerError
+		}
+		passwordHash = &hash
+	}
+
+	var expiresAt *time.Time
+	if body.ExpiresInDays != nil {
+		t := time.Now().Add(time.Duration(*body.ExpiresInDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+
+	publication, err := service.PublishNote(params.ID, passwordHash, expiresAt)
+	if err != nil {
+		log.Println("Error publishing note:", err)
+		return fiber.ErrInternalServerError
+	}
+	publication.URL = publicNoteURL(publication.Slug)
+
+	return c.JSON(publication)
+}
+
+func GetNotePublication(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NoteParams)
+
+	noteExists, err := service.CheckNoteExists(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error checking note exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !noteExists {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	publication, err := service.GetNotePublication(params.ID)
+	if err != nil {
+		log.Println("Error getting note publication:", err)
+		return fiber.ErrInternalServerError
+	}
+	if publication == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: "Note is not published.",
+		})
+	}
+	publication.URL = publicNoteURL(publication.Slug)
+
+	return c.JSON(publication)
+}
+
+func UnpublishNote(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NoteParams)
+
+	noteExists, err := service.CheckNoteExists(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error checking note exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !noteExists {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	result, err := service.UnpublishNote(params.ID)
+	if err != nil {
+		log.Println("Error unpublishing note:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: "Note is not published.",
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Note unpublished.",
+	})
+}
+
+// GetPublicNote serves a published note without authentication, as HTML to browsers and as JSON
+// otherwise. The format query parameter overrides the Accept header. Protected notes take the
+// password from the X-Note-Password header or, for the HTML form, from a POST body.
+func GetPublicNote(c *fiber.Ctx) error {
+	params := c.Locals("params").(*model.PublicNoteParams)
+	html := wantsHTML(c)
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set("X-Robots-Tag", "noindex")
+
+	note, err := service.GetPublishedNote(params.Slug)
+	if err != nil {
+		log.Println("Error getting published note:", err)
+		return fiber.ErrInternalServerError
+	}
+	if note == nil {
+		return publicNoteError(c, html, fiber.StatusNotFound, noteNotFound)
+	}
+
+	if note.Password != nil {
+		password := c.Get("X-Note-Password")
+		if c.Method() == fiber.MethodPost {
+			body := new(model.PublicNotePassword)
+			if err := c.BodyParser(body); err == nil {
+				password = body.Password
+			}
+		}
+		if password == "" {
+			return publicNotePasswordForm(c, html, "")
+		}
+
+		retryAfter, err := service.GetLoginRetryAfter(service.PublicationKeys(params.Slug, c.IP())...)
+		if err != nil {
+			log.Println("Error checking publication throttle:", err)
+			return fiber.ErrInternalServerError
+		}
+		if retryAfter > 0 {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			return publicNoteError(
+				c,
+				html,
+				fiber.StatusTooManyRequests,
+				"Too many wrong passwords. Try again later.",
+			)
+		}
+
+		match, err := argon2id.ComparePasswordAndHash(password, *note.Password)
+		if err != nil {
+			log.Println("Error comparing password and hash:", err)
+			return fiber.ErrInternalServerError
+		}
+		if !match {
+			if _, err = service.RecordPublicationPasswordFailure(params.Slug, c.IP()); err != nil {
+				log.Println("Error recording publication password failure:", err)
+				return fiber.ErrInternalServerError
+			}
+			return publicNotePasswordForm(c, html, invalidPassword)
+		}
+	}
+
+	if !html {
+		return c.JSON(note)
+	}
+	return renderPublicPage(c, fiber.StatusOK, publicPage{
+		Title:      note.Title,
+		Author:     note.Author,
+		UpdatedAt:  note.UpdatedAt.Format("January 2, 2006"),
+		Paragraphs: paragraphs(note.Content),
+	})
+}
+
+func publicNoteURL(slug string) string {
+	return fmt.Sprintf("%s/public/%s", config.AppURL, slug)
+}
+
+func wantsHTML(c *fiber.Ctx) bool {
+	switch c.Query("format") {
+	case "html":
+		return true
+	case "json":
+		return false
+	}
+	return c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextHTML) == fiber.MIMETextHTML
+}
+
+func publicNoteError(c *fiber.Ctx, html bool, status int, message string) error {
+	if !html {
+		return c.Status(status).JSON(model.Response{
+			Message: message,
+		})
+	}
+	return renderPublicPage(c, status, publicPage{Title: message})
+}
+
+func publicNotePasswordForm(c *fiber.Ctx, html bool, message string) error {
+	if !html {
+		if message == "" {
+			message = "Password is required."
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(model.Response{
+			Message: message,
+		})
+	}
+	return renderPublicPage(c, fiber.StatusUnauthorized, publicPage{
+		Title:         "Password required",
+		PasswordForm:  true,
+		PasswordError: message,
+	})
+}
+
+type publicPage struct {
+	Title         string
+	Author        *string
+	UpdatedAt     string
+	Paragraphs    []string
+	PasswordForm  bool
+	PasswordError string
+}
+
+var publicPageTemplate = template.Must(template.New("public").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 42rem; margin: 3rem auto; padding: 0 1rem; font: 1.05rem/1.6 system-ui, sans-serif; color: #222; }
+p { white-space: pre-wrap; overflow-wrap: anywhere; }
+.meta { color: #666; font-size: 0.9rem; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<article>
+<h1>{{.Title}}</h1>
+{{- if .UpdatedAt}}
+<p class="meta">{{with .Author}}By {{.}} · {{end}}Updated {{.UpdatedAt}}</p>
+{{- end}}
+{{- range .Paragraphs}}
+<p>{{.}}</p>
+{{- end}}
+{{- if .PasswordForm}}
+<form method="post">
+{{- if .PasswordError}}
+<p class="error">{{.PasswordError}}</p>
+{{- end}}
+<input type="password" name="password" placeholder="Password" required autofocus>
+<button type="submit">View note</button>
+</form>
+{{- end}}
+</article>
+</body>
+</html>
+`))
+
+func renderPublicPage(c *fiber.Ctx, status int, page publicPage) error {
+	buf := new(bytes.Buffer)
+	if err := publicPageTemplate.Execute(buf, page); err != nil {
+		log.Println("Error rendering public page:", err)
+		return fiber.ErrInternalServerError
+	}
+	c.Set(
+		fiber.HeaderContentSecurityPolicy,
+		"default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'",
+	)
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(status).Send(buf.Bytes())
+}
+
+// paragraphs splits note content on blank lines. Line breaks within a paragraph are kept by the
+// page style.
+func paragraphs(content *string) []string {
+	if content == nil {
+		return nil
+	}
+	var result []string
+	for _, p := range strings.Split(strings.ReplaceAll(*content, "\r\n", "\n"), "\n\n") {
+		if p = strings.Trim(p, "\n"); strings.TrimSpace(p) != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
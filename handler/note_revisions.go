@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"log"
+
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetNoteRevisions(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NoteParams).ID
+
+	role, err := service.GetNoteRole(id, auth.ID)
+	if err != nil {
+		log.Println("Error getting note role:", err)
+		return fiber.ErrInternalServerError
+	}
+	if role == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	revisions, err := service.GetNoteRevisions(id)
+	if err != nil {
+		log.Println("Error getting note revisions:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(revisions)
+}
+
+func GetNoteRevisionByID(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NoteRevisionParams)
+
+	role, err := service.GetNoteRole(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error getting note role:", err)
+		return fiber.ErrInternalServerError
+	}
+	if role == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	revision, err := service.GetNoteRevisionByID(params.ID, params.RevisionID)
+	if err != nil {
+		log.Println("Error getting note revision by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if revision == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: revisionNotFound,
+		})
+	}
+
+	return c.JSON(revision)
+}
+
+func RestoreNoteRevision(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NoteRevisionParams)
+
+	role, err := service.GetNoteRole(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error getting note role:", err)
+		return fiber.ErrInternalServerError
+	}
+	if role == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+	if *role == "viewer" {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: "Viewers can't restore revisions.",
+		})
+	}
+
+	result, err := service.RestoreNoteRevision(params.ID, params.RevisionID, auth.ID)
+	if err != nil {
+		log.Println("Error restoring note revision:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: revisionNotFound,
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Note revision restored.",
+	})
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"log"
+
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func UpdateNoteState(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NoteParams).ID
+	body := c.Locals("body").(*model.UpdateNoteState)
+
+	role, err := service.GetNoteRole(id, auth.ID)
+	if err != nil {
+		log.Println("Error getting note role:", err)
+		return fiber.ErrInternalServerError
+	}
+	if role == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	state, err := service.UpdateNoteState(id, auth.ID, body)
+	if err != nil {
+		log.Println("Error updating note state:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(state)
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"log"
+	"time"
+
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TransferNote(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NoteParams)
+	body := c.Locals("body").(*model.CreateNoteTransfer)
+
+	isOwner, err := service.CheckIsNoteOwner(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error checking note owner:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !isOwner {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	if body.UserID == auth.ID {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(model.Response{
+			Message: "You already own this note.",
+		})
+	}
+
+	isMember, err := service.CheckIsDirectNoteMember(params.ID, body.UserID)
+	if err != nil {
+		log.Println("Error checking note member:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !isMember {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(model.Response{
+			Message: "Ownership can only be transferred to a member of the note.",
+		})
+	}
+
+	err = service.CreateNoteTransfer(
+		params.ID,
+		auth.ID,
+		body.UserID,
+		time.Now().Add(noteTransferTTL),
+	)
+	if err != nil {
+		log.Println("Error creating note transfer:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(model.Response{
+		Message: "Transfer requested. The note changes owner once the member accepts.",
+	})
+}
+
+func GetNoteTransfers(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	transfers, err := service.GetNoteTransfers(auth.ID)
+	if err != nil {
+		log.Println("Error getting note transfers:", err)
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(transfers)
+}
+
+func GetSentNoteTransfers(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	transfers, err := service.GetSentNoteTransfers(auth.ID)
+	if err != nil {
+		log.Println("Error getting sent note transfers:", err)
+		return fiber.ErrInternalServerError
+	}
+	return c.JSON(transfers)
+}
+
+func RespondNoteTransfer(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NoteTransferParams).ID
+
+	body := new(model.RespondNoteTransfer)
+	if err := c.BodyParser(body); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(model.Response{
+			Message: validationErr,
+			Error: map[string]string{
+				"accept": "Accept must be a boolean.",
+			},
+		})
+	}
+
+	transfer, err := service.GetNoteTransferByID(id, auth.ID)
+	if err != nil {
+		log.Println("Error getting note transfer by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if transfer == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteTransferNotFound,
+		})
+	}
+	if !transfer.ExpiresAt.After(time.Now()) {
+		if err = service.ExpireNoteTransfer(transfer.ID); err != nil {
+			log.Println("Error expiring note transfer:", err)
+			return fiber.ErrInternalServerError
+		}
+		return c.Status(fiber.StatusGone).JSON(model.Response{
+			Message: "Transfer has expired.",
+		})
+	}
+
+	if !body.Accept {
+		if _, err = service.DeclineNoteTransfer(transfer.ID, auth.ID); err != nil {
+			log.Println("Error declining note transfer:", err)
+			return fiber.ErrInternalServerError
+		}
+		return c.JSON(model.Response{
+			Message: "Transfer declined.",
+		})
+	}
+
+	result, err := service.AcceptNoteTransfer(transfer)
+	if err != nil {
+		log.Println("Error accepting note transfer:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusGone).JSON(model.Response{
+			Message: "Transfer is no longer valid.",
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Transfer accepted. You now own the note.",
+	})
+}
+
+func CancelNoteTransfer(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NoteTransferParams).ID
+
+	result, err := service.CancelNoteTransfer(id, auth.ID)
+	if err != nil {
+		log.Println("Error cancelling note transfer:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteTransferNotFound,
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Transfer cancelled.",
+	})
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func CreateNotebook(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	body := c.Locals("body").(*model.NotebookInput)
+
+	if body.ParentID != nil {
+		valid, err := service.CheckNotebookParentValid(*body.ParentID, nil, auth.ID)
+		if err != nil {
+			log.Println("Error checking notebook parent:", err)
+			return fiber.ErrInternalServerError
+		}
+		if !valid {
+			return c.Status(fiber.StatusNotFound).JSON(model.Response{
+				Message: "Parent notebook not found.",
+			})
+		}
+	}
+
+	notebook, err := service.CreateNotebook(auth.ID, body)
+	if err != nil {
+		log.Println("Error creating notebook:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(notebook)
+}
+
+func GetNotebooks(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+
+	notebooks, err := service.GetNotebooks(auth.ID)
+	if err != nil {
+		log.Println("Error getting notebooks:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(notebooks)
+}
+
+func GetNotebookByID(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NotebookParams).ID
+
+	notebook, err := service.GetNotebookByID(id, auth.ID)
+	if err != nil {
+		log.Println("Error getting notebook by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if notebook == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: notebookNotFound,
+		})
+	}
+
+	return c.JSON(notebook)
+}
+
+func UpdateNotebook(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NotebookParams).ID
+	body := c.Locals("body").(*model.NotebookInput)
+
+	if body.ParentID != nil {
+		valid, err := service.CheckNotebookParentValid(*body.ParentID, &id, auth.ID)
+		if err != nil {
+			log.Println("Error checking notebook parent:", err)
+			return fiber.ErrInternalServerError
+		}
+		if !valid {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(model.Response{
+				Message: "Parent notebook not found or is inside this notebook.",
+			})
+		}
+	}
+
+	result, err := service.UpdateNotebook(id, auth.ID, body)
+	if err != nil {
+		log.Println("Error updating notebook:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: notebookNotFound,
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Notebook updated.",
+	})
+}
+
+func DeleteNotebook(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NotebookParams).ID
+
+	result, err := service.DeleteNotebook(id, auth.ID)
+	if err != nil {
+		log.Println("Error deleting notebook:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: notebookNotFound,
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Notebook deleted.",
+	})
+}
+
+func AddNotebookMember(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NotebookParams).ID
+	body := c.Locals("body").(*model.AddNotebookMember)
+
+	if auth.Email == body.Email {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(model.Response{
+			Message: "You can't share a notebook with yourself.",
+		})
+	}
+
+	isOwner, err := service.CheckIsNotebookOwner(id, auth.ID)
+	if err != nil {
+		log.Println("Error checking notebook owner:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !isOwner {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: notebookNotFound,
+		})
+	}
+
+	targetUserID, err := service.GetUserIDByEmail(body.Email)
+	if err != nil {
+		log.Println("Error getting user ID by email:", err)
+		return fiber.ErrInternalServerError
+	}
+	if targetUserID == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: fmt.Sprintf("User with email '%s' is not found.", body.Email),
+		})
+	}
+
+	if err = service.AddNotebookMember(id, *targetUserID, body.Role); err != nil {
+		log.Println("Error adding notebook member:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(model.Response{
+		Message: "Notebook shared.",
+	})
+}
+
+func UpdateNotebookMemberRole(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NotebookMemberParams)
+	body := c.Locals("body").(*model.UpdateNoteMemberRole)
+
+	isOwner, err := service.CheckIsNotebookOwner(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error checking notebook owner:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !isOwner {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: notebookNotFound,
+		})
+	}
+
+	result, err := service.UpdateNotebookMemberRole(params.ID, params.MemberID, body.Role)
+	if err != nil {
+		log.Println("Error updating notebook member role:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: "Notebook or member not found.",
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Notebook member role updated.",
+	})
+}
+
+func RemoveNotebookMember(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NotebookMemberParams)
+
+	isOwner, err := service.CheckIsNotebookOwner(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error checking notebook owner:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !isOwner {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: notebookNotFound,
+		})
+	}
+
+	result, err := service.RemoveNotebookMember(params.ID, params.MemberID)
+	if err != nil {
+		log.Println("Error removing notebook member:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: "Notebook or member not found.",
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Notebook member removed.",
+	})
+}
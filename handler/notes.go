@@ -7,6 +7,7 @@ import (
 	"github.com/amiftachulh/notez-api/service"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 func CreateNote(c *fiber.Ctx) error {
@@ -53,6 +54,7 @@ func GetNoteByID(c *fiber.Ctx) error {
 		})
 	}
 
+	c.Set(fiber.HeaderETag, noteETag(note.Version))
 	return c.JSON(note)
 }
 
@@ -61,17 +63,33 @@ func UpdateNoteByID(c *fiber.Ctx) error {
 	id := c.Locals("params").(*model.NoteParams).ID
 	body := c.Locals("body").(*model.NoteInput)
 
-	result, err := service.UpdateNoteByID(body, id, auth.ID)
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return notePreconditionFailed(c, id, auth.ID)
+	}
+
+	newVersion, err := service.UpdateNoteByID(body, id, auth.ID, version)
 	if err != nil {
 		log.Println("Error updating note:", err)
 		return fiber.ErrInternalServerError
 	}
-	if !result {
+	if newVersion == nil {
+		if version != nil {
+			role, err := service.GetNoteRole(id, auth.ID)
+			if err != nil {
+				log.Println("Error getting note role:", err)
+				return fiber.ErrInternalServerError
+			}
+			if role != nil && *role != "viewer" {
+				return notePreconditionFailed(c, id, auth.ID)
+			}
+		}
 		return c.Status(fiber.StatusNotFound).JSON(model.Response{
 			Message: "Note not found.",
 		})
 	}
 
+	c.Set(fiber.HeaderETag, noteETag(*newVersion))
 	return c.JSON(model.Response{
 		Message: "Note updated.",
 	})
@@ -81,18 +99,88 @@ func DeleteNoteByID(c *fiber.Ctx) error {
 	auth := c.Locals("auth").(model.AuthUser)
 	id := c.Locals("params").(*model.NoteParams).ID
 
-	result, err := service.DeleteNoteByID(id, auth.ID)
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return notePreconditionFailed(c, id, auth.ID)
+	}
+
+	result, err := service.DeleteNoteByID(id, auth.ID, version)
 	if err != nil {
 		log.Println("Error deleting note:", err)
 		return fiber.ErrInternalServerError
 	}
 	if !result {
+		if version != nil {
+			isOwner, err := service.CheckIsNoteOwner(id, auth.ID)
+			if err != nil {
+				log.Println("Error checking note owner:", err)
+				return fiber.ErrInternalServerError
+			}
+			if isOwner {
+				return notePreconditionFailed(c, id, auth.ID)
+			}
+		}
 		return c.Status(fiber.StatusNotFound).JSON(model.Response{
 			Message: "Note not found.",
 		})
 	}
 
 	return c.JSON(model.Response{
-		Message: "Note deleted.",
+		Message: "Note moved to trash.",
+	})
+}
+
+func MoveNote(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NoteParams).ID
+	body := c.Locals("body").(*model.MoveNote)
+
+	if body.NotebookID != nil {
+		isOwner, err := service.CheckIsNotebookOwner(*body.NotebookID, auth.ID)
+		if err != nil {
+			log.Println("Error checking notebook owner:", err)
+			return fiber.ErrInternalServerError
+		}
+		if !isOwner {
+			return c.Status(fiber.StatusNotFound).JSON(model.Response{
+				Message: notebookNotFound,
+			})
+		}
+	}
+
+	result, err := service.MoveNote(id, auth.ID, body.NotebookID)
+	if err != nil {
+		log.Println("Error moving note:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: "Note not found.",
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Note moved.",
+	})
+}
+
+// notePreconditionFailed responds with 412 and the current server copy of the note so the client
+// can merge its changes and retry with the new ETag.
+func notePreconditionFailed(c *fiber.Ctx, id, userID uuid.UUID) error {
+	note, err := service.GetNoteByID(id, userID)
+	if err != nil {
+		log.Println("Error getting note by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if note == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: "Note not found.",
+		})
+	}
+
+	c.Set(fiber.HeaderETag, noteETag(note.Version))
+	return c.Status(fiber.StatusPreconditionFailed).JSON(model.NotePreconditionFailedResponse{
+		Message: "Note has been modified by someone else.",
+		Current: note,
 	})
 }
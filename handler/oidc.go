@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/amiftachulh/notez-api/config"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/oidc"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// OIDCLogin starts single sign-on by redirecting the browser to the identity provider. The
+// state is also kept in a cookie so the callback only completes in the browser that started it.
+func OIDCLogin(c *fiber.Ctx) error {
+	query := c.Locals("query").(*model.OIDCLoginQuery)
+
+	if oidc.Default == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: ssoNotConfigured,
+		})
+	}
+
+	state, err := oidc.NewNonce()
+	if err != nil {
+		log.Println("Error generating OIDC state:", err)
+		return fiber.ErrInternalServerError
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		log.Println("Error generating OIDC nonce:", err)
+		return fiber.ErrInternalServerError
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		log.Println("Error generating PKCE verifier:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	authURL, err := oidc.Default.AuthCodeURL(c.Context(), state, nonce, challenge)
+	if err != nil {
+		log.Println("Error building OIDC authorization URL:", err)
+		return fiber.ErrBadGateway
+	}
+
+	expiresAt := time.Now().Add(oidcStateTTL)
+	err = service.CreateOIDCState(state, &model.OIDCState{
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		Remember:     query.RememberMe,
+	}, expiresAt)
+	if err != nil {
+		log.Println("Error creating OIDC state:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	cookie := new(fiber.Cookie)
+	cookie.Name = "oidc_state"
+	cookie.Value = state
+	cookie.Expires = expiresAt
+	cookie.HTTPOnly = true
+	cookie.Secure = true
+	cookie.SameSite = fiber.CookieSameSiteLaxMode
+	c.Cookie(cookie)
+
+	return c.Redirect(authURL)
+}
+
+// OIDCCallback completes single sign-on. The browser arrives here from the identity provider,
+// so results are reported by redirecting back to the frontend.
+func OIDCCallback(c *fiber.Ctx) error {
+	query := c.Locals("query").(*model.OIDCCallbackQuery)
+
+	if oidc.Default == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: ssoNotConfigured,
+		})
+	}
+
+	stateCookie := c.Cookies("oidc_state")
+	c.ClearCookie("oidc_state")
+
+	if query.Error != "" {
+		return ssoRedirectError(c, "sso_cancelled")
+	}
+	if query.State == "" || query.Code == "" || query.State != stateCookie {
+		return ssoRedirectError(c, "sso_invalid_state")
+	}
+
+	state, err := service.ConsumeOIDCState(query.State)
+	if err != nil {
+		log.Println("Error consuming OIDC state:", err)
+		return fiber.ErrInternalServerError
+	}
+	if state == nil {
+		return ssoRedirectError(c, "sso_invalid_state")
+	}
+
+	claims, err := oidc.Default.Exchange(c.Context(), query.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		log.Println("Error exchanging OIDC code:", err)
+		return ssoRedirectError(c, "sso_failed")
+	}
+
+	userID, err := service.GetUserIDByIdentity(claims.Issuer, claims.Subject)
+	if err != nil {
+		log.Println("Error getting user by identity:", err)
+		return fiber.ErrInternalServerError
+	}
+	if userID == nil {
+		// Accounts are matched by email only when the provider vouches for the address, otherwise
+		// anyone able to set an arbitrary email at the provider could take over local accounts.
+		if claims.Email == "" || !claims.EmailVerified {
+			return ssoRedirectError(c, "sso_email_not_verified")
+		}
+		if userID, err = linkOrCreateSSOUser(c, claims); err != nil {
+			log.Println("Error linking SSO user:", err)
+			return fiber.ErrInternalServerError
+		}
+	}
+
+	user, err := service.GetAuthUserByID(*userID)
+	if err != nil {
+		log.Println("Error getting user by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if user == nil {
+		return ssoRedirectError(c, "sso_failed")
+	}
+	if user.Disabled {
+		return ssoRedirectError(c, "account_disabled")
+	}
+	if user.PasswordResetRequired {
+		return ssoRedirectError(c, "password_reset_required")
+	}
+
+	if user.TwoFactorEnabled {
+		challenge, err := service.CreateLoginChallenge(user.ID, state.Remember, time.Now().Add(loginChallengeTTL))
+		if err != nil {
+			log.Println("Error creating login challenge:", err)
+			return fiber.ErrInternalServerError
+		}
+		// The challenge goes in the fragment so it isn't sent to servers or written to logs.
+		return c.Redirect(config.AppURL + "/login/2fa#challenge=" + url.QueryEscape(challenge))
+	}
+
+	if err = setSession(c, user, state.Remember); err != nil {
+		log.Println("Error creating session:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.Redirect(config.AppURL + "/")
+}
+
+// linkOrCreateSSOUser links the provider account to the local user with the same email, or
+// creates a new user for it.
+func linkOrCreateSSOUser(c *fiber.Ctx, claims *oidc.Claims) (*uuid.UUID, error) {
+	// Both paths may need a password nobody knows: new users sign in through the provider, and
+	// unverified local accounts lose the password someone else may have chosen.
+	hash, err := randomPasswordHash()
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := service.GetUserIDByEmail(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if userID != nil {
+		reset, err := service.LinkIdentity(*userID, claims.Issuer, claims.Subject, claims.Email, hash)
+		if err != nil {
+			return nil, err
+		}
+		if reset {
+			details := map[string]interface{}{"issuer": claims.Issuer}
+			if err = service.RecordAudit(userID, nil, "sso_link_credentials_reset", c.IP(), details); err != nil {
+				log.Println("Error recording audit entry:", err)
+			}
+		}
+		return userID, nil
+	}
+
+	var name *string
+	if claims.Name != "" {
+		name = &claims.Name
+	}
+	id, err := service.CreateOIDCUser(claims.Email, name, hash, claims.Issuer, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func randomPasswordHash() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hashPassword(base64.RawURLEncoding.EncodeToString(secret))
+}
+
+func ssoRedirectError(c *fiber.Ctx, code string) error {
+	return c.Redirect(config.AppURL + "/login?error=" + code)
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/amiftachulh/notez-api/config"
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/middleware"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/oidc"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// recordingDriver is a database driver that accepts every statement and records its arguments,
+// enough for handlers that only write.
+type recordingDriver struct {
+	mu    sync.Mutex
+	execs [][]driver.Value
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return recordingConn{d}, nil }
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c recordingConn) Prepare(string) (driver.Stmt, error) { return recordingStmt(c), nil }
+func (c recordingConn) Close() error                        { return nil }
+func (c recordingConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type recordingStmt struct{ d *recordingDriver }
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.execs = append(s.d.execs, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func setupOIDCTest(t *testing.T) *recordingDriver {
+	var idp *httptest.Server
+	idp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	}))
+	t.Cleanup(idp.Close)
+
+	d := &recordingDriver{}
+	db.DB = sql.OpenDB(recordingConnector{d})
+	t.Cleanup(func() { db.DB.Close(); db.DB = nil })
+
+	oidc.Default = oidc.NewProvider(oidc.Config{
+		Issuer:      idp.URL,
+		ClientID:    "notez",
+		RedirectURL: "http://localhost/auth/oidc/callback",
+	})
+	t.Cleanup(func() { oidc.Default = nil })
+
+	config.AppURL = "https://app.example.com"
+	return d
+}
+
+type recordingConnector struct{ d *recordingDriver }
+
+func (c recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return recordingConn(c), nil
+}
+func (c recordingConnector) Driver() driver.Driver { return c.d }
+
+func newOIDCTestApp() *fiber.App {
+	app := fiber.New()
+	app.Get("/login", middleware.ValidateQuery(&model.OIDCLoginQuery{}), OIDCLogin)
+	app.Get("/callback", middleware.ValidateQuery(&model.OIDCCallbackQuery{}), OIDCCallback)
+	return app
+}
+
+func findCookie(res *http.Response, name string) *http.Cookie {
+	for _, cookie := range res.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestOIDCLoginSetsStateCookie(t *testing.T) {
+	d := setupOIDCTest(t)
+	app := newOIDCTestApp()
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != fiber.StatusFound {
+		t.Fatalf("status = %d, want %d", res.StatusCode, fiber.StatusFound)
+	}
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := location.Query().Get("state")
+	if state == "" {
+		t.Fatalf("authorization URL %s has no state", location)
+	}
+
+	cookie := findCookie(res, "oidc_state")
+	if cookie == nil {
+		t.Fatal("oidc_state cookie not set")
+	}
+	if cookie.Value != state {
+		t.Errorf("cookie state = %q, want %q", cookie.Value, state)
+	}
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie flags = HttpOnly %t, Secure %t, SameSite %v", cookie.HttpOnly, cookie.Secure, cookie.SameSite)
+	}
+
+	// Only the hash of the state is stored.
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stored := false
+	for _, args := range d.execs {
+		if len(args) > 0 && args[0] == service.HashToken(state) {
+			stored = true
+		}
+		for _, arg := range args {
+			if arg == state {
+				t.Error("raw state written to the database")
+			}
+		}
+	}
+	if !stored {
+		t.Error("state hash not stored")
+	}
+}
+
+func TestOIDCCallbackRejectsState(t *testing.T) {
+	d := setupOIDCTest(t)
+	app := newOIDCTestApp()
+
+	tests := []struct {
+		name   string
+		query  string
+		cookie string
+		want   string
+	}{
+		{"missing cookie", "?code=code&state=state-1", "", "sso_invalid_state"},
+		{"mismatched cookie", "?code=code&state=state-1", "state-2", "sso_invalid_state"},
+		{"missing state", "?code=code", "state-1", "sso_invalid_state"},
+		{"missing code", "?state=state-1", "state-1", "sso_invalid_state"},
+		{"provider error", "?error=access_denied&state=state-1", "state-1", "sso_cancelled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/callback"+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "oidc_state", Value: tt.cookie})
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := config.AppURL + "/login?error=" + tt.want
+			if res.StatusCode != fiber.StatusFound || res.Header.Get("Location") != want {
+				t.Errorf("got %d %s, want redirect to %s", res.StatusCode, res.Header.Get("Location"), want)
+			}
+			if cookie := findCookie(res, "oidc_state"); cookie == nil || cookie.Value != "" {
+				t.Error("oidc_state cookie not cleared")
+			}
+		})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.execs) != 0 {
+		t.Errorf("rejected callbacks touched the database %d times", len(d.execs))
+	}
+}
+
+func TestOIDCCallbackNotConfigured(t *testing.T) {
+	app := newOIDCTestApp()
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/callback?code=code&state=state", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != fiber.StatusNotFound {
+		t.Errorf("status = %d, want %d", res.StatusCode, fiber.StatusNotFound)
+	}
+	if !strings.Contains(res.Header.Get("Content-Type"), "json") {
+		t.Errorf("content type = %s, want JSON", res.Header.Get("Content-Type"))
+	}
+}
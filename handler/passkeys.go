@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/amiftachulh/notez-api/config"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+	"github.com/amiftachulh/notez-api/webauthn"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func relyingParty() webauthn.RelyingParty {
+	return webauthn.RelyingParty{
+		ID:      config.WebAuthnRPID,
+		Name:    config.WebAuthnRPName,
+		Origins: config.WebAuthnOrigins,
+	}
+}
+
+func BeginPasskeyRegistration(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+
+	exclude, err := service.GetPasskeyDescriptors(auth.ID)
+	if err != nil {
+		log.Println("Error getting passkeys:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		log.Println("Error generating WebAuthn challenge:", err)
+		return fiber.ErrInternalServerError
+	}
+	expiresAt := time.Now().Add(webauthnChallengeTTL)
+	if err = service.CreateWebAuthnChallenge(challenge, &auth.ID, "registration", expiresAt); err != nil {
+		log.Println("Error creating WebAuthn challenge:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	displayName := auth.Email
+	if auth.Name != nil {
+		displayName = *auth.Name
+	}
+	return c.JSON(relyingParty().CreationOptions(challenge, auth.ID[:], auth.Email, displayName, exclude))
+}
+
+func FinishPasskeyRegistration(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	body := c.Locals("body").(*model.PasskeyRegistration)
+	response := body.Credential.Response
+
+	challenge, err := webauthn.Challenge(response.ClientDataJSON)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.Response{
+			Message: passkeyVerificationFail,
+		})
+	}
+
+	stored, err := service.ConsumeWebAuthnChallenge(challenge, "registration")
+	if err != nil {
+		log.Println("Error consuming WebAuthn challenge:", err)
+		return fiber.ErrInternalServerError
+	}
+	if stored == nil || stored.UserID == nil || *stored.UserID != auth.ID {
+		return c.Status(fiber.StatusBadRequest).JSON(model.Response{
+			Message: invalidWebAuthnChallenge,
+		})
+	}
+
+	cred, err := relyingParty().VerifyRegistration(challenge, response.ClientDataJSON, response.AttestationObject)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.Response{
+			Message: passkeyVerificationFail,
+		})
+	}
+
+	exists, err := service.CheckPasskeyCredentialExists(cred.ID)
+	if err != nil {
+		log.Println("Error checking passkey exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if exists {
+		return c.Status(fiber.StatusConflict).JSON(model.Response{
+			Message: "Passkey is already registered.",
+		})
+	}
+
+	name := body.Name
+	if name == "" {
+		name = "Passkey"
+	}
+	passkey, err := service.CreatePasskey(auth.ID, name, cred, response.Transports)
+	if err != nil {
+		log.Println("Error creating passkey:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(passkey)
+}
+
+func BeginPasskeyLogin(c *fiber.Ctx) error {
+	body := c.Locals("body").(*model.PasskeyLoginBegin)
+
+	// Without an email the browser offers any discoverable passkey for this site. With one the
+	// allow list is narrowed to that user's passkeys, but unknown emails get the same empty list
+	// so the response doesn't reveal which emails are registered.
+	allow := []webauthn.CredentialDescriptor{}
+	if body.Email != "" {
+		userID, err := service.GetUserIDByEmail(body.Email)
+		if err != nil {
+			log.Println("Error getting user by email:", err)
+			return fiber.ErrInternalServerError
+		}
+		if userID != nil {
+			if allow, err = service.GetPasskeyDescriptors(*userID); err != nil {
+				log.Println("Error getting passkeys:", err)
+				return fiber.ErrInternalServerError
+			}
+		}
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		log.Println("Error generating WebAuthn challenge:", err)
+		return fiber.ErrInternalServerError
+	}
+	expiresAt := time.Now().Add(webauthnChallengeTTL)
+	if err = service.CreateWebAuthnChallenge(challenge, nil, "login", expiresAt); err != nil {
+		log.Println("Error creating WebAuthn challenge:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(relyingParty().RequestOptions(challenge, allow))
+}
+
+func FinishPasskeyLogin(c *fiber.Ctx) error {
+	body := c.Locals("body").(*model.PasskeyLogin)
+	response := body.Credential.Response
+
+	challenge, err := webauthn.Challenge(response.ClientDataJSON)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(model.Response{
+			Message: passkeyVerificationFail,
+		})
+	}
+
+	stored, err := service.ConsumeWebAuthnChallenge(challenge, "login")
+	if err != nil {
+		log.Println("Error consuming WebAuthn challenge:", err)
+		return fiber.ErrInternalServerError
+	}
+	if stored == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(model.Response{
+			Message: invalidWebAuthnChallenge,
+		})
+	}
+
+	passkey, err := service.GetPasskeyByCredentialID(body.Credential.RawID)
+	if err != nil {
+		log.Println("Error getting passkey:", err)
+		return fiber.ErrInternalServerError
+	}
+	if passkey == nil ||
+		(len(response.UserHandle) > 0 && string(response.UserHandle) != string(passkey.UserID[:])) {
+		return c.Status(fiber.StatusUnauthorized).JSON(model.Response{
+			Message: passkeyVerificationFail,
+		})
+	}
+
+	signCount, err := relyingParty().VerifyAssertion(
+		challenge,
+		response.ClientDataJSON,
+		response.AuthenticatorData,
+		response.Signature,
+		passkey.PublicKey,
+		passkey.SignCount,
+	)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCount) {
+			log.Println("Passkey sign count did not increase, possible cloned authenticator:", passkey.ID)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(model.Response{
+			Message: passkeyVerificationFail,
+		})
+	}
+
+	updated, err := service.UpdatePasskeyUsage(passkey.ID, passkey.SignCount, signCount)
+	if err != nil {
+		log.Println("Error updating passkey usage:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !updated {
+		return c.Status(fiber.StatusUnauthorized).JSON(model.Response{
+			Message: passkeyVerificationFail,
+		})
+	}
+
+	user, err := service.GetAuthUserByID(passkey.UserID)
+	if err != nil {
+		log.Println("Error getting user by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if user == nil {
+		return fiber.ErrUnauthorized
+	}
+	if user.Disabled {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: accountDisabled,
+		})
+	}
+	if user.PasswordResetRequired {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: passwordResetRequired,
+		})
+	}
+
+	return startSession(c, user, body.RememberMe)
+}
+
+func GetPasskeys(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+
+	passkeys, err := service.GetPasskeys(auth.ID)
+	if err != nil {
+		log.Println("Error getting passkeys:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(passkeys)
+}
+
+func RenamePasskey(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.PasskeyParams)
+	body := c.Locals("body").(*model.PasskeyInput)
+
+	result, err := service.RenamePasskey(params.ID, auth.ID, body.Name)
+	if err != nil {
+		log.Println("Error renaming passkey:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: passkeyNotFound,
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Passkey renamed.",
+	})
+}
+
+func DeletePasskey(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.PasskeyParams)
+
+	result, err := service.DeletePasskey(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error deleting passkey:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: passkeyNotFound,
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Passkey deleted.",
+	})
+}
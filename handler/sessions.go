@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"log"
+	"time"
+
+	"github.com/amiftachulh/notez-api/config"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetSessions(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+
+	sessions, err := service.GetSessions(auth.ID)
+	if err != nil {
+		log.Println("Error getting sessions:", err)
+		return fiber.ErrInternalServerError
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == auth.SessionID
+	}
+
+	return c.JSON(sessions)
+}
+
+func RevokeSession(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.SessionParams)
+
+	result, err := service.RevokeSession(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error revoking session:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: sessionNotFound,
+		})
+	}
+
+	if params.ID == auth.SessionID {
+		clearSessionCookie(c)
+	}
+
+	return c.JSON(model.Response{
+		Message: "Session revoked.",
+	})
+}
+
+func RevokeOtherSessions(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+
+	if _, err := service.RevokeOtherSessions(auth.ID, auth.SessionID); err != nil {
+		log.Println("Error revoking other sessions:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(model.Response{
+		Message: "Logged out of all other sessions.",
+	})
+}
+
+func clearSessionCookie(c *fiber.Ctx) {
+	cookie := new(fiber.Cookie)
+	cookie.Name = "session"
+	cookie.Value = ""
+	cookie.Expires = time.Now()
+	cookie.HTTPOnly = true
+	cookie.Secure = true
+	cookie.SameSite = config.CookieSameSite
+	c.Cookie(cookie)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"log"
+
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func CreateTag(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	body := c.Locals("body").(*model.TagInput)
+
+	exists, err := service.CheckTagNameExists(auth.ID, body.Name)
+	if err != nil {
+		log.Println("Error checking tag name exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if exists {
+		return c.Status(fiber.StatusConflict).JSON(model.Response{
+			Message: tagNameUsed,
+		})
+	}
+
+	tag, err := service.CreateTag(auth.ID, body.Name)
+	if err != nil {
+		log.Println("Error creating tag:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(tag)
+}
+
+func GetTags(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+
+	tags, err := service.GetTags(auth.ID)
+	if err != nil {
+		log.Println("Error getting tags:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(tags)
+}
+
+func RenameTag(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.TagParams).ID
+	body := c.Locals("body").(*model.TagInput)
+
+	exists, err := service.CheckTagNameExists(auth.ID, body.Name)
+	if err != nil {
+		log.Println("Error checking tag name exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if exists {
+		return c.Status(fiber.StatusConflict).JSON(model.Response{
+			Message: tagNameUsed,
+		})
+	}
+
+	tag, err := service.RenameTag(id, auth.ID, body.Name)
+	if err != nil {
+		log.Println("Error renaming tag:", err)
+		return fiber.ErrInternalServerError
+	}
+	if tag == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: tagNotFound,
+		})
+	}
+
+	return c.JSON(tag)
+}
+
+func DeleteTag(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.TagParams).ID
+
+	result, err := service.DeleteTag(id, auth.ID)
+	if err != nil {
+		log.Println("Error deleting tag:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: tagNotFound,
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Tag deleted.",
+	})
+}
+
+func AttachNoteTag(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NoteTagParams)
+
+	role, err := service.GetNoteRole(params.ID, auth.ID)
+	if err != nil {
+		log.Println("Error getting note role:", err)
+		return fiber.ErrInternalServerError
+	}
+	if role == nil {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: noteNotFound,
+		})
+	}
+
+	exists, err := service.CheckTagExists(params.TagID, auth.ID)
+	if err != nil {
+		log.Println("Error checking tag exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: tagNotFound,
+		})
+	}
+
+	if err = service.AttachNoteTag(params.ID, params.TagID); err != nil {
+		log.Println("Error attaching note tag:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(model.Response{
+		Message: "Tag attached to note.",
+	})
+}
+
+func DetachNoteTag(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	params := c.Locals("params").(*model.NoteTagParams)
+
+	exists, err := service.CheckTagExists(params.TagID, auth.ID)
+	if err != nil {
+		log.Println("Error checking tag exists:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: tagNotFound,
+		})
+	}
+
+	result, err := service.DetachNoteTag(params.ID, params.TagID)
+	if err != nil {
+		log.Println("Error detaching note tag:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: "Tag is not attached to note.",
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Tag detached from note.",
+	})
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/amiftachulh/notez-api/config"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetTrash(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	query := c.Locals("query").(*model.TrashQuery)
+
+	notes, total, err := service.GetTrashedNotes(auth.ID, query, config.TrashRetention)
+	if err != nil {
+		log.Println("Error getting trashed notes:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(model.PaginationResponse{
+		Total: total,
+		Items: notes,
+	})
+}
+
+func RestoreNote(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NoteParams).ID
+
+	result, err := service.RestoreNote(id, auth.ID)
+	if err != nil {
+		log.Println("Error restoring note:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: "Note not found in trash.",
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Note restored.",
+	})
+}
+
+func DeleteTrashedNote(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	id := c.Locals("params").(*model.NoteParams).ID
+
+	result, err := service.DeleteTrashedNote(id, auth.ID)
+	if err != nil {
+		log.Println("Error deleting trashed note:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusNotFound).JSON(model.Response{
+			Message: "Note not found in trash.",
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Note permanently deleted.",
+	})
+}
+
+func EmptyTrash(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+
+	deleted, err := service.EmptyTrash(auth.ID)
+	if err != nil {
+		log.Println("Error emptying trash:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(model.Response{
+		Message: fmt.Sprintf("%d notes permanently deleted.", deleted),
+	})
+}
@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"log"
+
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
+	"github.com/amiftachulh/notez-api/totp"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/gofiber/fiber/v2"
+)
+
+func LoginTwoFactor(c *fiber.Ctx) error {
+	body := c.Locals("body").(*model.LoginTwoFactor)
+
+	pending, err := service.GetLoginChallenge(body.Challenge)
+	if err != nil {
+		log.Println("Error getting login challenge:", err)
+		return fiber.ErrInternalServerError
+	}
+	if pending == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(model.Response{
+			Message: "Login challenge is invalid or has expired. Sign in again.",
+		})
+	}
+
+	user, err := service.GetAuthUserByID(pending.UserID)
+	if err != nil {
+		log.Println("Error getting user by ID:", err)
+		return fiber.ErrInternalServerError
+	}
+	if user == nil {
+		return fiber.ErrUnauthorized
+	}
+
+	// The second factor shares the password's throttle, otherwise signing in again for a fresh
+	// challenge would allow unlimited guesses.
+	accountKey := service.LoginAccountKey(user.Email)
+	retryAfter, err := service.GetLoginRetryAfter(accountKey, service.LoginIPKey(c.IP()))
+	if err != nil {
+		log.Println("Error checking login throttle:", err)
+		return fiber.ErrInternalServerError
+	}
+	if retryAfter > 0 {
+		return tooManyLoginAttempts(c, retryAfter)
+	}
+
+	var valid bool
+	if body.RecoveryCode != "" {
+		valid, err = service.UseRecoveryCode(pending.UserID, body.RecoveryCode)
+	} else {
+		valid, err = service.VerifyTOTP(pending.UserID, body.Code)
+	}
+	if err != nil {
+		log.Println("Error verifying two-factor code:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !valid {
+		if err = service.RecordLoginChallengeFailure(body.Challenge); err != nil {
+			log.Println("Error recording login challenge failure:", err)
+			return fiber.ErrInternalServerError
+		}
+		if err = recordLoginFailure(c, user.Email, user); err != nil {
+			log.Println("Error recording login failure:", err)
+			return fiber.ErrInternalServerError
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(model.Response{
+			Message: invalidTwoFactorCode,
+		})
+	}
+
+	consumed, err := service.DeleteLoginChallenge(body.Challenge)
+	if err != nil {
+		log.Println("Error deleting login challenge:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !consumed {
+		return c.Status(fiber.StatusUnauthorized).JSON(model.Response{
+			Message: "Login challenge is invalid or has expired. Sign in again.",
+		})
+	}
+
+	if err = service.ClearLoginFailures(accountKey); err != nil {
+		log.Println("Error clearing login failures:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	if user.Disabled {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: accountDisabled,
+		})
+	}
+	if user.PasswordResetRequired {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: passwordResetRequired,
+		})
+	}
+
+	return startSession(c, user, pending.Remember)
+}
+
+func SetupTwoFactor(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+
+	if auth.TwoFactorEnabled {
+		return c.Status(fiber.StatusConflict).JSON(model.Response{
+			Message: "Two-factor authentication is already enabled.",
+		})
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Println("Error generating TOTP secret:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	result, err := service.SetPendingTOTPSecret(auth.ID, secret)
+	if err != nil {
+		log.Println("Error setting TOTP secret:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusConflict).JSON(model.Response{
+			Message: "Two-factor authentication is already enabled.",
+		})
+	}
+
+	return c.JSON(model.TwoFactorSetup{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, auth.Email, secret),
+	})
+}
+
+func ConfirmTwoFactor(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	body := c.Locals("body").(*model.TwoFactorCode)
+
+	codes, err := service.ConfirmTOTP(auth.ID, body.Code)
+	if err != nil {
+		log.Println("Error confirming TOTP:", err)
+		return fiber.ErrInternalServerError
+	}
+	if codes == nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(model.Response{
+			Message: invalidTwoFactorCode,
+		})
+	}
+
+	return c.JSON(model.RecoveryCodes{RecoveryCodes: codes})
+}
+
+func DisableTwoFactor(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	body := c.Locals("body").(*model.TwoFactorPassword)
+
+	match, err := checkPassword(auth, body.Password)
+	if err != nil {
+		log.Println("Error comparing password and hash:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !match {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(model.Response{
+			Message: invalidPassword,
+		})
+	}
+
+	result, err := service.DisableTOTP(auth.ID)
+	if err != nil {
+		log.Println("Error disabling TOTP:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !result {
+		return c.Status(fiber.StatusConflict).JSON(model.Response{
+			Message: "Two-factor authentication is not enabled.",
+		})
+	}
+
+	return c.JSON(model.Response{
+		Message: "Two-factor authentication disabled.",
+	})
+}
+
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	body := c.Locals("body").(*model.TwoFactorPassword)
+
+	match, err := checkPassword(auth, body.Password)
+	if err != nil {
+		log.Println("Error comparing password and hash:", err)
+		return fiber.ErrInternalServerError
+	}
+	if !match {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(model.Response{
+			Message: invalidPassword,
+		})
+	}
+
+	codes, err := service.RegenerateRecoveryCodes(auth.ID)
+	if err != nil {
+		log.Println("Error regenerating recovery codes:", err)
+		return fiber.ErrInternalServerError
+	}
+	if codes == nil {
+		return c.Status(fiber.StatusConflict).JSON(model.Response{
+			Message: "Two-factor authentication is not enabled.",
+		})
+	}
+
+	return c.JSON(model.RecoveryCodes{RecoveryCodes: codes})
+}
+
+// checkPassword compares the password against the stored hash of the authenticated user, which
+// middleware.Authenticate doesn't load.
+func checkPassword(auth model.AuthUser, password string) (bool, error) {
+	user, err := service.GetUserByID(auth.ID)
+	if err != nil || user == nil {
+		return false, err
+	}
+	return argon2id.ComparePasswordAndHash(password, user.Password)
+}
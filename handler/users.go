@@ -50,9 +50,9 @@ func UpdateUserEmail(c *fiber.Ctx) error {
 		})
 	}
 
-	result, err := service.UpdateUserEmail(auth.ID, body.Email)
+	result, err := service.SetPendingEmail(auth.ID, body.Email)
 	if err != nil {
-		log.Println("Error updating user email:", err)
+		log.Println("Error setting pending email:", err)
 		return fiber.ErrInternalServerError
 	}
 	if !result {
@@ -61,8 +61,35 @@ func UpdateUserEmail(c *fiber.Ctx) error {
 		})
 	}
 
+	if err = sendEmailVerification(auth.ID, body.Email); err != nil {
+		log.Println("Error creating email verification token:", err)
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(model.Response{
+		Message: "Verification email sent to the new address. The email is updated once it is confirmed.",
+	})
+}
+
+func ResendEmailVerification(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+
+	email := auth.Email
+	if auth.PendingEmail != nil {
+		email = *auth.PendingEmail
+	} else if auth.EmailVerifiedAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(model.Response{
+			Message: "Email is already verified.",
+		})
+	}
+
+	if err := sendEmailVerification(auth.ID, email); err != nil {
+		log.Println("Error creating email verification token:", err)
+		return fiber.ErrInternalServerError
+	}
+
 	return c.JSON(model.Response{
-		Message: "User email updated.",
+		Message: "Verification email sent.",
 	})
 }
 
@@ -121,6 +148,13 @@ func UpdateUserPassword(c *fiber.Ctx) error {
 		})
 	}
 
+	if body.RevokeOtherSessions {
+		if _, err = service.RevokeOtherSessions(auth.ID, auth.SessionID); err != nil {
+			log.Println("Error revoking other sessions:", err)
+			return fiber.ErrInternalServerError
+		}
+	}
+
 	return c.JSON(model.Response{
 		Message: "User password updated.",
 	})
@@ -1,6 +1,14 @@
 package handler
 
-import "github.com/alexedwards/argon2id"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/gofiber/fiber/v2"
+)
 
 func hashPassword(password string) (string, error) {
 	hash, err := argon2id.CreateHash(password, &argon2id.Params{
@@ -15,3 +23,42 @@ func hashPassword(password string) (string, error) {
 	}
 	return hash, nil
 }
+
+func noteETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion parses the If-Match header into a note version. It returns nil when the header
+// is missing or "*", and false when the header is not a note ETag.
+func ifMatchVersion(c *fiber.Ctx) (*int, bool) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return nil, false
+	}
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil {
+		return nil, false
+	}
+	return &version, true
+}
+
+// formatTTL describes a token lifetime for emails, e.g. "1 hour" or "14 days", in the largest
+// unit that divides it evenly.
+func formatTTL(d time.Duration) string {
+	n, unit := int64(d/time.Minute), "minute"
+	switch {
+	case d%(24*time.Hour) == 0:
+		n, unit = int64(d/(24*time.Hour)), "day"
+	case d%time.Hour == 0:
+		n, unit = int64(d/time.Hour), "hour"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
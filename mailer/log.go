@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LogMailer writes messages to the log, or to one file per message when Dir is set. It is meant
+// for local development and tests where no SMTP server is available.
+type LogMailer struct {
+	Dir string
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+
+	if m.Dir == "" {
+		log.Print("Email:\n" + content)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf(
+		"%s_%s.txt",
+		time.Now().Format("20060102T150405.000000000"),
+		strings.NewReplacer("@", "_at_", "/", "_").Replace(msg.To),
+	)
+	return os.WriteFile(filepath.Join(m.Dir, name), []byte(content), 0o644)
+}
@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"log"
+
+	"github.com/amiftachulh/notez-api/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+var Default Mailer
+
+func Setup() {
+	switch config.MailerDriver {
+	case "smtp":
+		Default = &SMTPMailer{
+			Host:     config.SMTPHost,
+			Port:     config.SMTPPort,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+			From:     config.MailFrom,
+		}
+	case "", "log":
+		Default = &LogMailer{Dir: config.MailLogDir}
+	default:
+		log.Fatalln("Unknown MAILER:", config.MailerDriver)
+	}
+}
+
+// SendAsync sends the message in the background so request latency doesn't reveal whether an
+// email was sent.
+func SendAsync(msg Message) {
+	go func() {
+		if err := Default.Send(msg); err != nil {
+			log.Println("Error sending email:", err)
+		}
+	}()
+}
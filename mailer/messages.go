@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/amiftachulh/notez-api/config"
+)
+
+func appLink(path string, token string) string {
+	return fmt.Sprintf("%s%s?token=%s", config.AppURL, path, url.QueryEscape(token))
+}
+
+func PasswordResetMessage(to, token string, expiresIn string) Message {
+	return Message{
+		To:      to,
+		Subject: "Reset your Notez password",
+		Body: fmt.Sprintf(
+			"Someone requested a password reset for your Notez account.\n\n"+
+				"Open the link below to choose a new password. It expires in %s and can only be used once.\n\n"+
+				"%s\n\n"+
+				"If you didn't request this, you can ignore this email.",
+			expiresIn,
+			appLink("/reset-password", token),
+		),
+	}
+}
+
+func EmailVerificationMessage(to, token string, expiresIn string) Message {
+	return Message{
+		To:      to,
+		Subject: "Verify your Notez email address",
+		Body: fmt.Sprintf(
+			"Confirm that this address belongs to your Notez account by opening the link below. "+
+				"It expires in %s.\n\n"+
+				"%s\n\n"+
+				"If you didn't request this, you can ignore this email.",
+			expiresIn,
+			appLink("/verify-email", token),
+		),
+	}
+}
+
+func AccountLockedMessage(to, token string, lockedFor string) Message {
+	return Message{
+		To:      to,
+		Subject: "Your Notez account was temporarily locked",
+		Body: fmt.Sprintf(
+			"Sign-in to your Notez account was locked for %s after several failed password attempts.\n\n"+
+				"If this was you, open the link below to unlock it now.\n\n"+
+				"%s\n\n"+
+				"If it wasn't you, someone may be guessing your password. Consider changing it once "+
+				"you are signed in.",
+			lockedFor,
+			appLink("/unlock-account", token),
+		),
+	}
+}
+
+func NoteInvitationMessage(to, inviter, noteTitle, token string, expiresIn string) Message {
+	return Message{
+		To:      to,
+		Subject: fmt.Sprintf("%s invited you to a note on Notez", inviter),
+		Body: fmt.Sprintf(
+			"%s invited you to collaborate on \"%s\" in Notez.\n\n"+
+				"Open the link below to create your account and join the note. It expires in %s.\n\n"+
+				"%s\n\n"+
+				"If you don't know the sender, you can ignore this email.",
+			inviter,
+			noteTitle,
+			expiresIn,
+			appLink("/invitation", token),
+		),
+	}
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"github.com/amiftachulh/notez-api/config"
 	"github.com/amiftachulh/notez-api/db"
 	"github.com/amiftachulh/notez-api/handler"
+	"github.com/amiftachulh/notez-api/mailer"
+	"github.com/amiftachulh/notez-api/oidc"
 	"github.com/amiftachulh/notez-api/route"
+	"github.com/amiftachulh/notez-api/service"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -15,9 +19,13 @@ import (
 func main() {
 	config.Setup()
 	db.Setup()
+	mailer.Setup()
+	oidc.Setup()
+	service.StartTrashPurger(config.TrashRetention, time.Hour)
 
 	app := fiber.New(fiber.Config{
 		ErrorHandler: handler.ErrorHandler,
+		ProxyHeader:  config.ProxyHeader,
 	})
 
 	app.Use(logger.New())
@@ -28,6 +36,7 @@ func main() {
 			return exists
 		},
 		AllowCredentials: true,
+		ExposeHeaders:    fiber.HeaderETag,
 	}))
 
 	route.Setup(app)
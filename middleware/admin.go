@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"github.com/amiftachulh/notez-api/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireAdmin rejects users without the admin role.
+func RequireAdmin(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	if auth.Role != "admin" {
+		return fiber.ErrForbidden
+	}
+	return c.Next()
+}
@@ -4,30 +4,45 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"strings"
+	"time"
 
+	"github.com/amiftachulh/notez-api/config"
 	"github.com/amiftachulh/notez-api/db"
 	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/service"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// sessionTouchInterval limits how often last_seen_at is written so that most requests only read
+// the session.
+const sessionTouchInterval = time.Minute
+
 func Authenticate(c *fiber.Ctx) error {
+	if header := c.Get(fiber.HeaderAuthorization); header != "" {
+		return authenticateToken(c, header)
+	}
+
 	sessionID := c.Cookies("session")
 	if sessionID == "" {
 		return fiber.ErrUnauthorized
 	}
 
 	var u model.AuthUser
+	var lastSeenAt, absoluteExpiresAt time.Time
+	var remember bool
 	query := `
-		SELECT u.id, u.name, u.email, u.role, u.created_at, u.updated_at, s.expires_at
+		SELECT u.id, u.name, u.email, u.email_verified_at, u.pending_email, u.totp_enabled_at IS NOT NULL, u.role, u.created_at, u.updated_at, s.expires_at, s.public_id, s.last_seen_at,
+			s.remember, s.absolute_expires_at, s.csrf_token
 		FROM sessions s
 		JOIN users u
 		ON s.user_id = u.id
-		WHERE s.id = $1 AND s.expires_at > now()
+		WHERE s.id = $1 AND s.expires_at > now() AND u.disabled_at IS NULL
 	`
 	err := db.DB.
 		QueryRow(query, sessionID).
-		Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt, &u.ExpiresAt)
+		Scan(&u.ID, &u.Name, &u.Email, &u.EmailVerifiedAt, &u.PendingEmail, &u.TwoFactorEnabled, &u.Role, &u.CreatedAt, &u.UpdatedAt, &u.ExpiresAt, &u.SessionID, &lastSeenAt, &remember, &absoluteExpiresAt, &u.CSRFToken)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fiber.ErrUnauthorized
@@ -36,6 +51,57 @@ func Authenticate(c *fiber.Ctx) error {
 		return err
 	}
 
+	// Sliding expiration: the session is extended by the idle timeout once doing so moves the
+	// expiry by more than the refresh threshold, which also limits how often the cookie is
+	// rewritten.
+	renewedExpiresAt := service.SessionExpiry(remember, absoluteExpiresAt)
+	renew := renewedExpiresAt.Sub(u.ExpiresAt) > config.SessionRefreshThreshold
+	if renew || time.Since(lastSeenAt) > sessionTouchInterval {
+		expiresAt := u.ExpiresAt
+		if renew {
+			expiresAt = renewedExpiresAt
+		}
+		query = `
+			UPDATE sessions SET last_seen_at = NOW(), ip_address = NULLIF($1, ''), expires_at = $2
+			WHERE id = $3
+		`
+		if _, err := db.DB.Exec(query, c.IP(), expiresAt, sessionID); err != nil {
+			log.Println("Error updating session:", err)
+		} else if renew {
+			u.ExpiresAt = expiresAt
+			if remember {
+				cookie := new(fiber.Cookie)
+				cookie.Name = "session"
+				cookie.Value = sessionID
+				cookie.Expires = expiresAt
+				cookie.HTTPOnly = true
+				cookie.Secure = true
+				cookie.SameSite = config.CookieSameSite
+				c.Cookie(cookie)
+			}
+		}
+	}
+
 	c.Locals("auth", u)
 	return c.Next()
 }
+
+// authenticateToken handles "Authorization: Bearer <token>" with a personal access token.
+func authenticateToken(c *fiber.Ctx, header string) error {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return fiber.ErrUnauthorized
+	}
+
+	u, err := service.GetUserByAPIToken(token)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	if u == nil {
+		return fiber.ErrUnauthorized
+	}
+
+	c.Locals("auth", *u)
+	return c.Next()
+}
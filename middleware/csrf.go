@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/amiftachulh/notez-api/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const HeaderCSRFToken = "X-CSRF-Token"
+
+// VerifyCSRF requires state-changing requests authenticated with the session cookie to carry
+// the session's CSRF token in the X-CSRF-Token header. A cross-site form or script can make
+// the browser attach the cookie but can't read the token. Bearer token requests aren't sent
+// automatically by browsers and are exempt. It must run after Authenticate.
+func VerifyCSRF(c *fiber.Ctx) error {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return c.Next()
+	}
+
+	auth := c.Locals("auth").(model.AuthUser)
+	if auth.TokenID != nil {
+		return c.Next()
+	}
+
+	token := c.Get(HeaderCSRFToken)
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(auth.CSRFToken)) != 1 {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: "Missing or invalid CSRF token.",
+		})
+	}
+	return c.Next()
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/amiftachulh/notez-api/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireScope rejects requests authenticated with a personal access token that wasn't granted
+// the scope. Session requests have full access.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		auth := c.Locals("auth").(model.AuthUser)
+		if auth.TokenID != nil && !slices.Contains(auth.Scopes, scope) {
+			return c.Status(fiber.StatusForbidden).JSON(model.Response{
+				Message: "Token is missing the " + scope + " scope.",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireSession rejects requests authenticated with a personal access token, for routes that
+// manage the account itself.
+func RequireSession(c *fiber.Ctx) error {
+	auth := c.Locals("auth").(model.AuthUser)
+	if auth.TokenID != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: "This endpoint requires signing in. Access tokens can't be used.",
+		})
+	}
+	return c.Next()
+}
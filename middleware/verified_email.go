@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/amiftachulh/notez-api/config"
+	"github.com/amiftachulh/notez-api/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireVerifiedEmail rejects the request when the verified email policy is enabled and the
+// authenticated user hasn't verified their email yet.
+func RequireVerifiedEmail(c *fiber.Ctx) error {
+	if !config.RequireVerifiedEmail {
+		return c.Next()
+	}
+
+	auth := c.Locals("auth").(model.AuthUser)
+	if auth.EmailVerifiedAt == nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.Response{
+			Message: "Verify your email before sharing notes.",
+		})
+	}
+
+	return c.Next()
+}
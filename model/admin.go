@@ -0,0 +1,112 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/invopop/validation"
+)
+
+type AdminUserQuery struct {
+	Query    string `query:"q"         json:"q"`
+	Role     string `query:"role"      json:"role"`
+	Status   string `query:"status"    json:"status"`
+	Page     int    `query:"page"      json:"page"`
+	PageSize int    `query:"page_size" json:"page_size"`
+}
+
+func (q AdminUserQuery) New() interface{} {
+	return &AdminUserQuery{
+		Page:     1,
+		PageSize: 10,
+	}
+}
+
+func (q AdminUserQuery) Validate() error {
+	return validation.ValidateStruct(
+		&q,
+		validation.Field(
+			&q.Role,
+			validation.In("admin", "user").Error("Role must be either admin or user."),
+		),
+		validation.Field(
+			&q.Status,
+			validation.In("active", "disabled").Error("Status must be either active or disabled."),
+		),
+		validation.Field(
+			&q.Page,
+			validation.Min(1).Error("Page must be greater than 0."),
+		),
+		validation.Field(
+			&q.PageSize,
+			validation.Min(1).Error("Page size must be greater than 0."),
+			validation.Max(100).Error("Page size must be less than 100."),
+		),
+	)
+}
+
+type AdminUser struct {
+	ID                    uuid.UUID `json:"id"`
+	Name                  *string   `json:"name"`
+	Email                 string    `json:"email"`
+	Role                  string    `json:"role"`
+	EmailVerifiedAt       *string   `json:"email_verified_at"`
+	TwoFactorEnabled      bool      `json:"two_factor_enabled"`
+	DisabledAt            *string   `json:"disabled_at"`
+	PasswordResetRequired bool      `json:"password_reset_required"`
+	CreatedAt             string    `json:"created_at"`
+	UpdatedAt             string    `json:"updated_at"`
+}
+
+type AdminNoteCounts struct {
+	Owned   int `json:"owned"`
+	Shared  int `json:"shared"`
+	Trashed int `json:"trashed"`
+}
+
+type AdminUserDetail struct {
+	AdminUser
+	NoteCounts AdminNoteCounts `json:"note_counts"`
+	Sessions   []Session       `json:"sessions"`
+}
+
+type AdminUserParams struct {
+	ID uuid.UUID `param:"id"`
+}
+
+func (p AdminUserParams) New() interface{} {
+	return &AdminUserParams{}
+}
+
+// AdminDisableUser optionally hands the user's notes over when disabling them. TransferTo
+// receives every owned note, otherwise TransferNotes offers each shared note to its
+// longest-standing member. Recipients still have to accept.
+type AdminDisableUser struct {
+	TransferNotes bool       `json:"transfer_notes"`
+	TransferTo    *uuid.UUID `json:"transfer_to"`
+}
+
+func (a AdminDisableUser) New() interface{} {
+	return &AdminDisableUser{}
+}
+
+func (a AdminDisableUser) Validate() error {
+	return nil
+}
+
+type UpdateUserRole struct {
+	Role string `json:"role"`
+}
+
+func (u UpdateUserRole) New() interface{} {
+	return &UpdateUserRole{}
+}
+
+func (u UpdateUserRole) Validate() error {
+	return validation.ValidateStruct(
+		&u,
+		validation.Field(
+			&u.Role,
+			validation.Required.Error("Role is required."),
+			validation.In("admin", "user").Error("Role must be either admin or user."),
+		),
+	)
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/invopop/validation"
+)
+
+// APITokenScopes lists the scopes a personal access token can be granted. Routes without a scope,
+// such as profile and session management, can only be used with a session.
+var APITokenScopes = []interface{}{
+	"notes:read",
+	"notes:write",
+	"notebooks:read",
+	"notebooks:write",
+	"tags:read",
+	"tags:write",
+}
+
+type APIToken struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	Scopes     []string  `json:"scopes"`
+	ExpiresAt  *string   `json:"expires_at"`
+	LastUsedAt *string   `json:"last_used_at"`
+	CreatedAt  string    `json:"created_at"`
+}
+
+// CreatedAPIToken is returned once on creation. The plain token can't be retrieved later.
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+type APITokenInput struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int     `json:"expires_in_days"`
+}
+
+func (a APITokenInput) New() interface{} {
+	return &APITokenInput{}
+}
+
+func (a APITokenInput) Validate() error {
+	return validation.ValidateStruct(
+		&a,
+		validation.Field(
+			&a.Name,
+			validation.Required.Error("Name is required."),
+			validation.RuneLength(1, 64).Error("Name must be between 1 and 64 characters."),
+		),
+		validation.Field(
+			&a.Scopes,
+			validation.Required.Error("At least one scope is required."),
+			validation.Each(validation.In(APITokenScopes...).Error("Scope is not valid.")),
+		),
+		validation.Field(
+			&a.ExpiresInDays,
+			validation.When(
+				a.ExpiresInDays != nil,
+				validation.Min(1).Error("Expiry must be between 1 and 365 days."),
+				validation.Max(365).Error("Expiry must be between 1 and 365 days."),
+			),
+		),
+	)
+}
+
+type APITokenParams struct {
+	ID uuid.UUID `param:"id"`
+}
+
+func (p APITokenParams) New() interface{} {
+	return &APITokenParams{}
+}
@@ -13,6 +13,9 @@ type Register struct {
 	Email           string `json:"email"`
 	Password        string `json:"password"`
 	ConfirmPassword string `json:"confirm_password"`
+	// InvitationToken comes from a note invitation email. It proves ownership of the address, so
+	// the account starts out verified and the invitation is accepted.
+	InvitationToken string `json:"invitation_token,omitempty"`
 }
 
 func samePassword(str string) validation.RuleFunc {
@@ -49,8 +52,9 @@ func (r Register) Validate() error {
 }
 
 type Login struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	RememberMe bool   `json:"remember_me"`
 }
 
 func (l Login) New() interface{} {
@@ -64,13 +68,108 @@ func (l Login) Validate() error {
 	)
 }
 
+type ForgotPassword struct {
+	Email string `json:"email"`
+}
+
+func (f ForgotPassword) New() interface{} {
+	return &ForgotPassword{}
+}
+
+func (f ForgotPassword) Validate() error {
+	return validation.ValidateStruct(
+		&f,
+		validation.Field(
+			&f.Email,
+			validation.Required.Error("Email is required."),
+			is.Email.Error("Email is not valid."),
+		),
+	)
+}
+
+type ResetPassword struct {
+	Token           string `json:"token"`
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+func (r ResetPassword) New() interface{} {
+	return &ResetPassword{}
+}
+
+func (r ResetPassword) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.Token, validation.Required.Error("Token is required.")),
+		validation.Field(
+			&r.Password,
+			validation.Required.Error("Password is required."),
+			validation.RuneLength(8, 64).Error("Password must be between 8 and 64 characters."),
+			validation.Match(regexp.MustCompile(`^[^\p{Cc}]+$`)).
+				Error("Password can't contain invalid characters."),
+		),
+		validation.Field(&r.ConfirmPassword, validation.By(samePassword(r.Password))),
+	)
+}
+
 type AuthUser struct {
-	ID        uuid.UUID `json:"id"`
-	Name      *string   `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	ExpiresAt time.Time `json:"expires_at"`
+	ID               uuid.UUID  `json:"id"`
+	Name             *string    `json:"name"`
+	Email            string     `json:"email"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	PendingEmail     *string    `json:"pending_email"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	Password         string     `json:"-"`
+	// Disabled and PasswordResetRequired are only loaded at sign-in. Authenticate rejects
+	// disabled users outright.
+	Disabled              bool      `json:"-"`
+	PasswordResetRequired bool      `json:"-"`
+	Role                  string    `json:"role"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	// CSRFToken must be sent in the X-CSRF-Token header of state-changing requests made with
+	// the session cookie.
+	CSRFToken string `json:"csrf_token,omitempty"`
+	// SessionID is the public ID of the session the request was authenticated with.
+	SessionID uuid.UUID `json:"-"`
+	// TokenID and Scopes are set when the request was authenticated with a personal access
+	// token instead of a session.
+	TokenID *uuid.UUID `json:"-"`
+	Scopes  []string   `json:"-"`
+}
+
+type VerifyEmail struct {
+	Token string `json:"token"`
+}
+
+func (v VerifyEmail) New() interface{} {
+	return &VerifyEmail{}
+}
+
+func (v VerifyEmail) Validate() error {
+	return validation.ValidateStruct(
+		&v,
+		validation.Field(&v.Token, validation.Required.Error("Token is required.")),
+	)
+}
+
+type EmailVerification struct {
+	UserID uuid.UUID
+	Email  string
+}
+
+type UnlockAccount struct {
+	Token string `json:"token"`
+}
+
+func (u UnlockAccount) New() interface{} {
+	return &UnlockAccount{}
+}
+
+func (u UnlockAccount) Validate() error {
+	return validation.ValidateStruct(
+		&u,
+		validation.Field(&u.Token, validation.Required.Error("Token is required.")),
+	)
 }
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/invopop/validation"
+)
+
+type UserExport struct {
+	ExportedAt  string             `json:"exported_at"`
+	Profile     User               `json:"profile"`
+	Notes       []ExportNote       `json:"notes"`
+	Memberships []ExportMembership `json:"memberships"`
+	Invitations []ExportInvitation `json:"invitations"`
+}
+
+type ExportNote struct {
+	ID         uuid.UUID  `json:"id"`
+	NotebookID *uuid.UUID `json:"notebook_id"`
+	Title      string     `json:"title"`
+	Content    *string    `json:"content"`
+	Tags       TagList    `json:"tags"`
+	CreatedAt  string     `json:"created_at"`
+	UpdatedAt  string     `json:"updated_at"`
+	DeletedAt  *string    `json:"deleted_at"`
+}
+
+type ExportMembership struct {
+	NoteID    uuid.UUID `json:"note_id"`
+	Title     string    `json:"title"`
+	Owner     User      `json:"owner"`
+	Role      string    `json:"role"`
+	CreatedAt string    `json:"created_at"`
+}
+
+type ExportInvitation struct {
+	ID        uuid.UUID `json:"id"`
+	NoteID    uuid.UUID `json:"note_id"`
+	Title     string    `json:"title"`
+	Direction string    `json:"direction"`
+	User      User      `json:"user"`
+	Role      string    `json:"role"`
+	Status    string    `json:"status"`
+	CreatedAt string    `json:"created_at"`
+}
+
+type DeleteAccount struct {
+	Password string `json:"password"`
+	// SharedNotes decides what happens to owned notes that have members: "transfer" hands each
+	// one straight to its longest-standing editor (or viewer), "delete" removes them with the
+	// account.
+	SharedNotes string `json:"shared_notes"`
+}
+
+func (d DeleteAccount) New() interface{} {
+	return &DeleteAccount{
+		SharedNotes: "transfer",
+	}
+}
+
+func (d DeleteAccount) Validate() error {
+	return validation.ValidateStruct(
+		&d,
+		validation.Field(&d.Password, validation.Required.Error("Password is required.")),
+		validation.Field(
+			&d.SharedNotes,
+			validation.In("transfer", "delete").
+				Error("Shared notes must be either 'transfer' or 'delete'."),
+		),
+	)
+}
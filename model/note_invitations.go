@@ -42,10 +42,11 @@ func (p NoteInvitationParams) New() interface{} {
 }
 
 type NoteInvitation struct {
-	ID     uuid.UUID `json:"id"`
-	NoteID uuid.UUID `json:"note_id"`
-	UserID uuid.UUID `json:"user_id"`
-	Role   string    `json:"role"`
+	ID        uuid.UUID `json:"id"`
+	NoteID    uuid.UUID `json:"note_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 type noteWithoutUserID struct {
@@ -58,9 +59,38 @@ type NoteInvitationResponse struct {
 	Note      noteWithoutUserID `json:"note"`
 	Inviter   User              `json:"inviter"`
 	Role      string            `json:"role"`
+	ExpiresAt time.Time         `json:"expires_at"`
 	CreatedAt time.Time         `json:"created_at"`
 }
 
+type SentNoteInvitationResponse struct {
+	ID          uuid.UUID         `json:"id"`
+	Note        noteWithoutUserID `json:"note"`
+	Invitee     User              `json:"invitee"`
+	Role        string            `json:"role"`
+	Status      string            `json:"status"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+	RespondedAt *time.Time        `json:"responded_at"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
 type RespondNoteInvitation struct {
 	Accept bool `json:"accept"`
 }
+
+type PendingNoteInvitation struct {
+	ID        uuid.UUID `json:"id"`
+	NoteID    uuid.UUID `json:"note_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type PendingNoteInvitationResponse struct {
+	ID        uuid.UUID         `json:"id"`
+	Note      noteWithoutUserID `json:"note"`
+	Email     string            `json:"email"`
+	Role      string            `json:"role"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/invopop/validation"
+)
+
+type NoteJoinLink struct {
+	ID            uuid.UUID  `json:"id"`
+	NoteID        uuid.UUID  `json:"note_id"`
+	Role          string     `json:"role"`
+	MaxUses       *int       `json:"max_uses"`
+	UseCount      int        `json:"use_count"`
+	AllowedDomain *string    `json:"allowed_domain"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// CreatedNoteJoinLink is returned once on creation. The plain token can't be retrieved later.
+type CreatedNoteJoinLink struct {
+	NoteJoinLink
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+var domainRegex = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}$`)
+
+type NoteJoinLinkInput struct {
+	Role          string  `json:"role"`
+	MaxUses       *int    `json:"max_uses"`
+	ExpiresInDays *int    `json:"expires_in_days"`
+	AllowedDomain *string `json:"allowed_domain"`
+}
+
+func (l NoteJoinLinkInput) New() interface{} {
+	return &NoteJoinLinkInput{}
+}
+
+func (l NoteJoinLinkInput) Validate() error {
+	return validation.ValidateStruct(
+		&l,
+		validation.Field(
+			&l.Role,
+			validation.Required.Error("Role is required."),
+			validation.In("editor", "viewer").Error("Role must be either 'editor' or 'viewer'."),
+		),
+		validation.Field(
+			&l.MaxUses,
+			validation.When(
+				l.MaxUses != nil,
+				validation.Min(1).Error("Max uses must be between 1 and 1000."),
+				validation.Max(1000).Error("Max uses must be between 1 and 1000."),
+			),
+		),
+		validation.Field(
+			&l.ExpiresInDays,
+			validation.When(
+				l.ExpiresInDays != nil,
+				validation.Min(1).Error("Expiry must be between 1 and 365 days."),
+				validation.Max(365).Error("Expiry must be between 1 and 365 days."),
+			),
+		),
+		validation.Field(
+			&l.AllowedDomain,
+			validation.When(
+				l.AllowedDomain != nil,
+				validation.Match(domainRegex).Error("Allowed domain is not valid."),
+			),
+		),
+	)
+}
+
+type NoteJoinLinkParams struct {
+	ID     uuid.UUID `param:"id"`
+	LinkID uuid.UUID `param:"linkID"`
+}
+
+func (p NoteJoinLinkParams) New() interface{} {
+	return &NoteJoinLinkParams{}
+}
+
+type JoinParams struct {
+	Token string `param:"token"`
+}
+
+func (p JoinParams) New() interface{} {
+	return &JoinParams{}
+}
+
+type JoinNoteResponse struct {
+	Message string    `json:"message"`
+	NoteID  uuid.UUID `json:"note_id"`
+	Role    string    `json:"role"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"github.com/amiftachulh/notez-api/ot"
+	"github.com/google/uuid"
+)
+
+// LiveMessage is exchanged over the note live WebSocket.
+//
+// Clients send "op" messages with the revision they have seen and the operation they applied.
+// The server replies with "init" on connect, "ack" for the sender's own operations, "op" for
+// operations from other members, "join"/"leave" for presence, "role" when the client's role
+// changes and "error". An "op" without a user_id merges in a save made outside the live session.
+type LiveMessage struct {
+	Type     string        `json:"type"`
+	Revision int           `json:"revision"`
+	Ops      *ot.Operation `json:"ops,omitempty"`
+	Content  *string       `json:"content,omitempty"`
+	Role     string        `json:"role,omitempty"`
+	UserID   *uuid.UUID    `json:"user_id,omitempty"`
+	Users    []uuid.UUID   `json:"users,omitempty"`
+	Message  string        `json:"message,omitempty"`
+}
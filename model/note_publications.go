@@ -0,0 +1,73 @@
+package model
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/invopop/validation"
+)
+
+type NotePublication struct {
+	Slug              string     `json:"slug"`
+	URL               string     `json:"url"`
+	PasswordProtected bool       `json:"password_protected"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// PublishNote replaces the publication settings of the note. Leaving out the password makes the
+// note readable by anyone with the link.
+type PublishNote struct {
+	Password      *string `json:"password"`
+	ExpiresInDays *int    `json:"expires_in_days"`
+}
+
+func (p PublishNote) New() interface{} {
+	return &PublishNote{}
+}
+
+func (p PublishNote) Validate() error {
+	return validation.ValidateStruct(
+		&p,
+		validation.Field(
+			&p.Password,
+			validation.When(
+				p.Password != nil,
+				validation.RuneLength(4, 64).Error("Password must be between 4 and 64 characters."),
+				validation.Match(regexp.MustCompile(`^[^\p{Cc}]+$`)).
+					Error("Password can't contain invalid characters."),
+			),
+		),
+		validation.Field(
+			&p.ExpiresInDays,
+			validation.When(
+				p.ExpiresInDays != nil,
+				validation.Min(1).Error("Expiry must be between 1 and 365 days."),
+				validation.Max(365).Error("Expiry must be between 1 and 365 days."),
+			),
+		),
+	)
+}
+
+type PublicNoteParams struct {
+	Slug string `param:"slug"`
+}
+
+func (p PublicNoteParams) New() interface{} {
+	return &PublicNoteParams{}
+}
+
+type PublicNotePassword struct {
+	Password string `json:"password" form:"password"`
+}
+
+type PublicNote struct {
+	Title       string     `json:"title"`
+	Content     *string    `json:"content"`
+	Author      *string    `json:"author"`
+	PublishedAt time.Time  `json:"published_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Password    *string    `json:"-"`
+}
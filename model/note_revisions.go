@@ -0,0 +1,34 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+type NoteRevisionParams struct {
+	ID         uuid.UUID `param:"id"`
+	RevisionID uuid.UUID `param:"rev"`
+}
+
+func (p NoteRevisionParams) New() interface{} {
+	return &NoteRevisionParams{}
+}
+
+type NoteRevision struct {
+	ID        uuid.UUID `json:"id"`
+	NoteID    uuid.UUID `json:"note_id"`
+	Title     string    `json:"title"`
+	Content   *string   `json:"content,omitempty"`
+	Author    *User     `json:"author"`
+	CreatedAt string    `json:"created_at"`
+}
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type NoteRevisionDetail struct {
+	NoteRevision
+	CurrentTitle string     `json:"current_title"`
+	Diff         []DiffLine `json:"diff"`
+}
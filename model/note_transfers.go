@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/invopop/validation"
+)
+
+type CreateNoteTransfer struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (t CreateNoteTransfer) New() interface{} {
+	return &CreateNoteTransfer{}
+}
+
+func (t CreateNoteTransfer) Validate() error {
+	return validation.ValidateStruct(
+		&t,
+		validation.Field(&t.UserID, validation.Required.Error("User ID is required.")),
+	)
+}
+
+type NoteTransferParams struct {
+	ID uuid.UUID `param:"id"`
+}
+
+func (p NoteTransferParams) New() interface{} {
+	return &NoteTransferParams{}
+}
+
+type RespondNoteTransfer struct {
+	Accept bool `json:"accept"`
+}
+
+type NoteTransfer struct {
+	ID         uuid.UUID `json:"id"`
+	NoteID     uuid.UUID `json:"note_id"`
+	FromUserID uuid.UUID `json:"from_user_id"`
+	ToUserID   uuid.UUID `json:"to_user_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type NoteTransferResponse struct {
+	ID          uuid.UUID         `json:"id"`
+	Note        noteWithoutUserID `json:"note"`
+	From        User              `json:"from"`
+	To          User              `json:"to"`
+	Status      string            `json:"status"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+	RespondedAt *time.Time        `json:"responded_at"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
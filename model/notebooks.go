@@ -0,0 +1,98 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/invopop/validation"
+	"github.com/invopop/validation/is"
+)
+
+type Notebook struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+	Name      string     `json:"name"`
+	Role      *string    `json:"role,omitempty"`
+	CreatedAt string     `json:"created_at"`
+	UpdatedAt string     `json:"updated_at"`
+}
+
+type NotebookDetail struct {
+	Notebook
+	Owner    NoteMember   `json:"owner"`
+	Children []Notebook   `json:"children"`
+	Members  []NoteMember `json:"members"`
+}
+
+type NotebookInput struct {
+	Name     string     `json:"name"`
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+func (n NotebookInput) New() interface{} {
+	return &NotebookInput{}
+}
+
+func (n NotebookInput) Validate() error {
+	return validation.ValidateStruct(
+		&n,
+		validation.Field(
+			&n.Name,
+			validation.Required.Error("Name is required."),
+			validation.RuneLength(1, 100).Error("Name must be between 1 and 100 characters."),
+		),
+	)
+}
+
+type NotebookParams struct {
+	ID uuid.UUID `param:"id"`
+}
+
+func (p NotebookParams) New() interface{} {
+	return &NotebookParams{}
+}
+
+type NotebookMemberParams struct {
+	ID       uuid.UUID `param:"id"`
+	MemberID uuid.UUID `param:"memberID"`
+}
+
+func (p NotebookMemberParams) New() interface{} {
+	return &NotebookMemberParams{}
+}
+
+type AddNotebookMember struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+func (m AddNotebookMember) New() interface{} {
+	return &AddNotebookMember{}
+}
+
+func (m AddNotebookMember) Validate() error {
+	return validation.ValidateStruct(
+		&m,
+		validation.Field(
+			&m.Email,
+			validation.Required.Error("Email is required."),
+			is.Email.Error("Email is invalid."),
+		),
+		validation.Field(
+			&m.Role,
+			validation.Required.Error("Role is required."),
+			validation.In("editor", "viewer").Error("Role must be either 'editor' or 'viewer'."),
+		),
+	)
+}
+
+type MoveNote struct {
+	NotebookID *uuid.UUID `json:"notebook_id"`
+}
+
+func (m MoveNote) New() interface{} {
+	return &MoveNote{}
+}
+
+func (m MoveNote) Validate() error {
+	return nil
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"github.com/google/uuid"
 	"github.com/invopop/validation"
+	"github.com/invopop/validation/is"
 )
 
 const NOTE_MAX_CONTENT_BYTES = 5 * 1024 * 1024 // 5 MB
@@ -54,12 +55,19 @@ func (p NoteParams) New() interface{} {
 }
 
 type NoteQuery struct {
-	Query    string `query:"q"         json:"q"`
-	Page     int    `query:"page"      json:"page"`
-	PageSize int    `query:"page_size" json:"page_size"`
-	Sort     string `query:"sort"      json:"sort"`
-	Order    string `query:"order"     json:"order"`
-	Role     string `query:"role" json:"role"`
+	Query    string   `query:"q"         json:"q"`
+	Page     int      `query:"page"      json:"page"`
+	PageSize int      `query:"page_size" json:"page_size"`
+	Sort     string   `query:"sort"      json:"sort"`
+	Order    string   `query:"order"     json:"order"`
+	Role     string   `query:"role" json:"role"`
+	Mode     string   `query:"mode"      json:"mode"`
+	Tags     []string `query:"tags"      json:"tags"`
+	TagMatch string   `query:"tag_match" json:"tag_match"`
+	Notebook string   `query:"notebook_id" json:"notebook_id"`
+	Pinned   string   `query:"pinned"    json:"pinned"`
+	Archived string   `query:"archived"  json:"archived"`
+	Favorite string   `query:"favorite"  json:"favorite"`
 }
 
 func (q NoteQuery) New() interface{} {
@@ -68,6 +76,9 @@ func (q NoteQuery) New() interface{} {
 		PageSize: 10,
 		Sort:     "id",
 		Order:    "asc",
+		Mode:     "title",
+		TagMatch: "any",
+		Archived: "false",
 	}
 }
 
@@ -97,17 +108,56 @@ func (q NoteQuery) Validate() error {
 			validation.In("owner", "editor", "viewer").
 				Error("Invalid role. Allowed values: 'owner', 'editor', 'viewer'."),
 		),
+		validation.Field(
+			&q.Mode,
+			validation.In("title", "fulltext").
+				Error("Invalid mode. Allowed values: 'title', 'fulltext'."),
+		),
+		validation.Field(
+			&q.Tags,
+			validation.Length(0, 20).Error("Tags must be at most 20 items."),
+		),
+		validation.Field(
+			&q.TagMatch,
+			validation.In("any", "all").Error("Invalid tag match. Allowed values: 'any', 'all'."),
+		),
+		validation.Field(
+			&q.Notebook,
+			validation.When(
+				q.Notebook != "none",
+				is.UUID.Error("Notebook ID must be a valid UUID or 'none'."),
+			),
+		),
+		validation.Field(
+			&q.Pinned,
+			validation.In("true", "false").Error("Pinned must be either 'true' or 'false'."),
+		),
+		validation.Field(
+			&q.Archived,
+			validation.In("true", "false", "all").
+				Error("Archived must be either 'true', 'false' or 'all'."),
+		),
+		validation.Field(
+			&q.Favorite,
+			validation.In("true", "false").Error("Favorite must be either 'true' or 'false'."),
+		),
 	)
 }
 
 type NoteResponse struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Title     string    `json:"title"`
-	Content   *string   `json:"content"`
-	Role      *string   `json:"role,omitempty"`
-	CreatedAt string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	NotebookID *uuid.UUID `json:"notebook_id"`
+	Title      string     `json:"title"`
+	Content    *string    `json:"content"`
+	Role       *string    `json:"role,omitempty"`
+	Pinned     bool       `json:"pinned"`
+	Archived   bool       `json:"archived"`
+	Favorite   bool       `json:"favorite"`
+	Tags       TagList    `json:"tags"`
+	Snippet    *string    `json:"snippet,omitempty"`
+	CreatedAt  string     `json:"created_at"`
+	UpdatedAt  string     `json:"updated_at"`
 }
 
 type NoteMember struct {
@@ -119,12 +169,51 @@ type NoteMember struct {
 }
 
 type NoteDetail struct {
-	ID        uuid.UUID    `json:"id"`
-	Title     string       `json:"title"`
-	Content   *string      `json:"content"`
-	Role      *string      `json:"role"`
-	Owner     NoteMember   `json:"owner"`
-	Members   []NoteMember `json:"members"`
-	CreatedAt string       `json:"created_at"`
-	UpdatedAt string       `json:"updated_at"`
+	ID         uuid.UUID    `json:"id"`
+	Title      string       `json:"title"`
+	Content    *string      `json:"content"`
+	Role       *string      `json:"role"`
+	NotebookID *uuid.UUID   `json:"notebook_id"`
+	Owner      NoteMember   `json:"owner"`
+	Members    []NoteMember `json:"members"`
+	Tags       TagList      `json:"tags"`
+	Pinned     bool         `json:"pinned"`
+	Archived   bool         `json:"archived"`
+	Favorite   bool         `json:"favorite"`
+	Version    int          `json:"version"`
+	CreatedAt  string       `json:"created_at"`
+	UpdatedAt  string       `json:"updated_at"`
+}
+
+type NotePreconditionFailedResponse struct {
+	Message string      `json:"message"`
+	Current *NoteDetail `json:"current"`
+}
+
+type UpdateNoteState struct {
+	Pinned   *bool `json:"pinned"`
+	Archived *bool `json:"archived"`
+	Favorite *bool `json:"favorite"`
+}
+
+func (u UpdateNoteState) New() interface{} {
+	return &UpdateNoteState{}
+}
+
+func (u UpdateNoteState) Validate() error {
+	if u.Pinned == nil && u.Archived == nil && u.Favorite == nil {
+		return validation.Errors{
+			"state": validation.NewError(
+				"state_required",
+				"At least one of 'pinned', 'archived' or 'favorite' is required.",
+			),
+		}
+	}
+	return nil
+}
+
+type NoteState struct {
+	Pinned   bool `json:"pinned"`
+	Archived bool `json:"archived"`
+	Favorite bool `json:"favorite"`
 }
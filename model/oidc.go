@@ -0,0 +1,33 @@
+package model
+
+type OIDCLoginQuery struct {
+	RememberMe bool `query:"remember_me"`
+}
+
+func (o OIDCLoginQuery) New() interface{} {
+	return &OIDCLoginQuery{}
+}
+
+func (o OIDCLoginQuery) Validate() error {
+	return nil
+}
+
+type OIDCCallbackQuery struct {
+	Code  string `query:"code"`
+	State string `query:"state"`
+	Error string `query:"error"`
+}
+
+func (o OIDCCallbackQuery) New() interface{} {
+	return &OIDCCallbackQuery{}
+}
+
+func (o OIDCCallbackQuery) Validate() error {
+	return nil
+}
+
+type OIDCState struct {
+	Nonce        string
+	CodeVerifier string
+	Remember     bool
+}
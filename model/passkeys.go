@@ -0,0 +1,202 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/invopop/validation"
+	"github.com/invopop/validation/is"
+)
+
+// Base64URL is binary data encoded as base64url in JSON, as produced by
+// PublicKeyCredential.toJSON. Padded input is accepted too.
+type Base64URL []byte
+
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+type Passkey struct {
+	ID             uuid.UUID `json:"id"`
+	Name           string    `json:"name"`
+	Transports     []string  `json:"transports"`
+	BackupEligible bool      `json:"backup_eligible"`
+	BackedUp       bool      `json:"backed_up"`
+	LastUsedAt     *string   `json:"last_used_at"`
+	CreatedAt      string    `json:"created_at"`
+	UpdatedAt      string    `json:"updated_at"`
+}
+
+type PasskeyCredential struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	PublicKey []byte
+	SignCount uint32
+}
+
+type WebAuthnChallenge struct {
+	UserID *uuid.UUID
+}
+
+type AttestationResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AttestationObject Base64URL `json:"attestationObject"`
+	Transports        []string  `json:"transports"`
+}
+
+func (a AttestationResponse) Validate() error {
+	return validation.ValidateStruct(
+		&a,
+		validation.Field(&a.ClientDataJSON, validation.Required.Error("Client data is required.")),
+		validation.Field(
+			&a.AttestationObject,
+			validation.Required.Error("Attestation object is required."),
+		),
+		validation.Field(&a.Transports, validation.Length(0, 8)),
+	)
+}
+
+type RegistrationCredential struct {
+	ID       string              `json:"id"`
+	RawID    Base64URL           `json:"rawId"`
+	Type     string              `json:"type"`
+	Response AttestationResponse `json:"response"`
+}
+
+func (r RegistrationCredential) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.RawID, validation.Required.Error("Credential ID is required.")),
+		validation.Field(
+			&r.Type,
+			validation.Required.Error("Type is required."),
+			validation.In("public-key").Error("Type must be public-key."),
+		),
+		validation.Field(&r.Response),
+	)
+}
+
+type PasskeyRegistration struct {
+	Name       string                 `json:"name"`
+	Credential RegistrationCredential `json:"credential"`
+}
+
+func (p PasskeyRegistration) New() interface{} {
+	return &PasskeyRegistration{}
+}
+
+func (p PasskeyRegistration) Validate() error {
+	return validation.ValidateStruct(
+		&p,
+		validation.Field(
+			&p.Name,
+			validation.RuneLength(0, 64).Error("Name must be at most 64 characters."),
+		),
+		validation.Field(&p.Credential),
+	)
+}
+
+type AssertionResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AuthenticatorData Base64URL `json:"authenticatorData"`
+	Signature         Base64URL `json:"signature"`
+	UserHandle        Base64URL `json:"userHandle"`
+}
+
+func (a AssertionResponse) Validate() error {
+	return validation.ValidateStruct(
+		&a,
+		validation.Field(&a.ClientDataJSON, validation.Required.Error("Client data is required.")),
+		validation.Field(
+			&a.AuthenticatorData,
+			validation.Required.Error("Authenticator data is required."),
+		),
+		validation.Field(&a.Signature, validation.Required.Error("Signature is required.")),
+	)
+}
+
+type AssertionCredential struct {
+	ID       string            `json:"id"`
+	RawID    Base64URL         `json:"rawId"`
+	Type     string            `json:"type"`
+	Response AssertionResponse `json:"response"`
+}
+
+func (a AssertionCredential) Validate() error {
+	return validation.ValidateStruct(
+		&a,
+		validation.Field(&a.RawID, validation.Required.Error("Credential ID is required.")),
+		validation.Field(
+			&a.Type,
+			validation.Required.Error("Type is required."),
+			validation.In("public-key").Error("Type must be public-key."),
+		),
+		validation.Field(&a.Response),
+	)
+}
+
+type PasskeyLoginBegin struct {
+	Email string `json:"email"`
+}
+
+func (p PasskeyLoginBegin) New() interface{} {
+	return &PasskeyLoginBegin{}
+}
+
+func (p PasskeyLoginBegin) Validate() error {
+	return validation.ValidateStruct(
+		&p,
+		validation.Field(&p.Email, is.Email.Error("Email is not valid.")),
+	)
+}
+
+type PasskeyLogin struct {
+	Credential AssertionCredential `json:"credential"`
+	RememberMe bool                `json:"remember_me"`
+}
+
+func (p PasskeyLogin) New() interface{} {
+	return &PasskeyLogin{}
+}
+
+func (p PasskeyLogin) Validate() error {
+	return validation.ValidateStruct(&p, validation.Field(&p.Credential))
+}
+
+type PasskeyInput struct {
+	Name string `json:"name"`
+}
+
+func (p PasskeyInput) New() interface{} {
+	return &PasskeyInput{}
+}
+
+func (p PasskeyInput) Validate() error {
+	return validation.ValidateStruct(
+		&p,
+		validation.Field(
+			&p.Name,
+			validation.Required.Error("Name is required."),
+			validation.RuneLength(1, 64).Error("Name must be between 1 and 64 characters."),
+		),
+	)
+}
+
+type PasskeyParams struct {
+	ID uuid.UUID `param:"id"`
+}
+
+func (p PasskeyParams) New() interface{} {
+	return &PasskeyParams{}
+}
@@ -0,0 +1,21 @@
+package model
+
+import "github.com/google/uuid"
+
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	IPAddress  *string   `json:"ip_address"`
+	UserAgent  *string   `json:"user_agent"`
+	Current    bool      `json:"current"`
+	CreatedAt  string    `json:"created_at"`
+	LastSeenAt string    `json:"last_seen_at"`
+	ExpiresAt  string    `json:"expires_at"`
+}
+
+type SessionParams struct {
+	ID uuid.UUID `param:"id"`
+}
+
+func (p SessionParams) New() interface{} {
+	return &SessionParams{}
+}
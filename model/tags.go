@@ -0,0 +1,73 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/invopop/validation"
+)
+
+type Tag struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	NoteCount *int      `json:"note_count,omitempty"`
+	CreatedAt string    `json:"created_at,omitempty"`
+	UpdatedAt string    `json:"updated_at,omitempty"`
+}
+
+// TagList scans a JSON array of tags aggregated by the database.
+type TagList []Tag
+
+func (t *TagList) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t = TagList{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	default:
+		return fmt.Errorf("unsupported type for TagList: %T", src)
+	}
+}
+
+type TagInput struct {
+	Name string `json:"name"`
+}
+
+func (t TagInput) New() interface{} {
+	return &TagInput{}
+}
+
+func (t TagInput) Validate() error {
+	return validation.ValidateStruct(
+		&t,
+		validation.Field(
+			&t.Name,
+			validation.Required.Error("Name is required."),
+			validation.RuneLength(1, 50).Error("Name must be between 1 and 50 characters."),
+			validation.Match(regexp.MustCompile(`^[^,\p{Cc}]+$`)).
+				Error("Name can't contain commas or invalid characters."),
+		),
+	)
+}
+
+type TagParams struct {
+	ID uuid.UUID `param:"id"`
+}
+
+func (p TagParams) New() interface{} {
+	return &TagParams{}
+}
+
+type NoteTagParams struct {
+	ID    uuid.UUID `param:"id"`
+	TagID uuid.UUID `param:"tagID"`
+}
+
+func (p NoteTagParams) New() interface{} {
+	return &NoteTagParams{}
+}
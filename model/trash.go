@@ -0,0 +1,43 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/invopop/validation"
+)
+
+type TrashQuery struct {
+	Page     int `query:"page"      json:"page"`
+	PageSize int `query:"page_size" json:"page_size"`
+}
+
+func (q TrashQuery) New() interface{} {
+	return &TrashQuery{
+		Page:     1,
+		PageSize: 10,
+	}
+}
+
+func (q TrashQuery) Validate() error {
+	return validation.ValidateStruct(
+		&q,
+		validation.Field(
+			&q.Page,
+			validation.Min(1).Error("Page must be greater than 0."),
+		),
+		validation.Field(
+			&q.PageSize,
+			validation.Min(1).Error("Page size must be greater than 0."),
+			validation.Max(100).Error("Page size must be less than 100."),
+		),
+	)
+}
+
+type TrashedNote struct {
+	ID         uuid.UUID  `json:"id"`
+	NotebookID *uuid.UUID `json:"notebook_id"`
+	Title      string     `json:"title"`
+	CreatedAt  string     `json:"created_at"`
+	UpdatedAt  string     `json:"updated_at"`
+	DeletedAt  string     `json:"deleted_at"`
+	PurgeAt    string     `json:"purge_at"`
+}
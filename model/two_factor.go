@@ -0,0 +1,88 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/invopop/validation"
+)
+
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type LoginChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	Challenge         string `json:"challenge"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+// PendingLogin is a login waiting for its second factor.
+type PendingLogin struct {
+	UserID   uuid.UUID
+	Remember bool
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code"`
+}
+
+func (t TwoFactorCode) New() interface{} {
+	return &TwoFactorCode{}
+}
+
+func (t TwoFactorCode) Validate() error {
+	return validation.ValidateStruct(
+		&t,
+		validation.Field(&t.Code, validation.Required.Error("Code is required.")),
+	)
+}
+
+type TwoFactorPassword struct {
+	Password string `json:"password"`
+}
+
+func (t TwoFactorPassword) New() interface{} {
+	return &TwoFactorPassword{}
+}
+
+func (t TwoFactorPassword) Validate() error {
+	return validation.ValidateStruct(
+		&t,
+		validation.Field(&t.Password, validation.Required.Error("Password is required.")),
+	)
+}
+
+type LoginTwoFactor struct {
+	Challenge    string `json:"challenge"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (l LoginTwoFactor) New() interface{} {
+	return &LoginTwoFactor{}
+}
+
+func (l LoginTwoFactor) Validate() error {
+	return validation.ValidateStruct(
+		&l,
+		validation.Field(&l.Challenge, validation.Required.Error("Challenge is required.")),
+		validation.Field(
+			&l.Code,
+			validation.When(
+				l.RecoveryCode == "",
+				validation.Required.Error("Code or recovery code is required."),
+			),
+		),
+		validation.Field(
+			&l.RecoveryCode,
+			validation.When(
+				l.Code != "",
+				validation.Empty.Error("Provide either a code or a recovery code, not both."),
+			),
+		),
+	)
+}
@@ -59,9 +59,10 @@ func (u UpdateUserEmail) Validate() error {
 }
 
 type UpdateUserPassword struct {
-	CurrentPassword string `json:"current_password"`
-	Password        string `json:"password"`
-	ConfirmPassword string `json:"confirm_password"`
+	CurrentPassword     string `json:"current_password"`
+	Password            string `json:"password"`
+	ConfirmPassword     string `json:"confirm_password"`
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`
 }
 
 func (u UpdateUserPassword) New() interface{} {
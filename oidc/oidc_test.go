@@ -0,0 +1,420 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID = "notez"
+	testNonce    = "test-nonce"
+	testVerifier = "test-verifier"
+)
+
+// testIdP is a minimal identity provider serving discovery, JWKS and the token endpoint. The
+// token endpoint returns whatever ID token the test set last.
+type testIdP struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu        sync.Mutex
+	ecKeys    map[string]*ecdsa.PrivateKey
+	rsaKeys   map[string]*rsa.PrivateKey
+	idToken   string
+	jwksHits  int
+	tokenForm url.Values
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	idp := &testIdP{
+		t:       t,
+		ecKeys:  map[string]*ecdsa.PrivateKey{},
+		rsaKeys: map[string]*rsa.PrivateKey{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(metadata{
+			Issuer:                idp.server.URL,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			JWKSURI:               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+
+		idp.jwksHits++
+		var set jwkSet
+		for kid, key := range idp.ecKeys {
+			set.Keys = append(set.Keys, jwk{
+				Kty: "EC",
+				Kid: kid,
+				Use: "sig",
+				Crv: "P-256",
+				X:   base64.RawURLEncoding.EncodeToString(key.PublicKey.X.FillBytes(make([]byte, 32))),
+				Y:   base64.RawURLEncoding.EncodeToString(key.PublicKey.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+		for kid, key := range idp.rsaKeys {
+			set.Keys = append(set.Keys, jwk{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(exponentBytes(key.PublicKey.E)),
+			})
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+
+		idp.tokenForm = r.PostForm
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.idToken})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdP) fetches() int {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.jwksHits
+}
+
+func exponentBytes(e int) []byte {
+	return []byte{byte(e >> 16), byte(e >> 8), byte(e)}
+}
+
+func (idp *testIdP) provider() *Provider {
+	return NewProvider(Config{
+		Issuer:      idp.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/callback",
+		Scopes:      []string{"openid", "email"},
+	})
+}
+
+func (idp *testIdP) addECKey(kid string) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	idp.mu.Lock()
+	idp.ecKeys[kid] = key
+	idp.mu.Unlock()
+	return key
+}
+
+func (idp *testIdP) setToken(token string) {
+	idp.mu.Lock()
+	idp.idToken = token
+	idp.mu.Unlock()
+}
+
+// validClaims returns claims the provider accepts; tests change one field at a time.
+func (idp *testIdP) validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":            idp.server.URL,
+		"sub":            "user-1",
+		"aud":            testClientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          testNonce,
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "Test User",
+	}
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, c map[string]interface{}) string {
+	signed := encodeSegment(t, jwtHeader{Alg: "ES256", Kid: kid}) + "." + encodeSegment(t, c)
+	hash := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, c map[string]interface{}) string {
+	signed := encodeSegment(t, jwtHeader{Alg: "RS256", Kid: kid}) + "." + encodeSegment(t, c)
+	hash := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestExchange(t *testing.T) {
+	idp := newTestIdP(t)
+	key := idp.addECKey("ec-1")
+	p := idp.provider()
+
+	idp.setToken(signES256(t, key, "ec-1", idp.validClaims()))
+	c, err := p.Exchange(context.Background(), "code-1", testVerifier, testNonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := Claims{
+		Issuer:        idp.server.URL,
+		Subject:       "user-1",
+		Email:         "user@example.com",
+		EmailVerified: true,
+		Name:          "Test User",
+	}
+	if *c != want {
+		t.Errorf("claims = %+v, want %+v", *c, want)
+	}
+
+	idp.mu.Lock()
+	form := idp.tokenForm
+	idp.mu.Unlock()
+	if form.Get("code") != "code-1" || form.Get("code_verifier") != testVerifier ||
+		form.Get("client_id") != testClientID || form.Get("grant_type") != "authorization_code" {
+		t.Errorf("unexpected token request: %v", form)
+	}
+}
+
+func TestExchangeRS256(t *testing.T) {
+	idp := newTestIdP(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.mu.Lock()
+	idp.rsaKeys["rsa-1"] = key
+	idp.mu.Unlock()
+	p := idp.provider()
+
+	idp.setToken(signRS256(t, key, "rsa-1", idp.validClaims()))
+	if _, err := p.Exchange(context.Background(), "code", testVerifier, testNonce); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+}
+
+func TestExchangeRejectsClaims(t *testing.T) {
+	idp := newTestIdP(t)
+	key := idp.addECKey("ec-1")
+
+	tests := []struct {
+		name   string
+		modify func(c map[string]interface{})
+		nonce  string
+	}{
+		{"wrong issuer", func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }, testNonce},
+		{"missing subject", func(c map[string]interface{}) { delete(c, "sub") }, testNonce},
+		{"wrong audience", func(c map[string]interface{}) { c["aud"] = "other-client" }, testNonce},
+		{
+			"multiple audiences without azp",
+			func(c map[string]interface{}) { c["aud"] = []string{testClientID, "other-client"} },
+			testNonce,
+		},
+		{
+			"multiple audiences with wrong azp",
+			func(c map[string]interface{}) {
+				c["aud"] = []string{testClientID, "other-client"}
+				c["azp"] = "other-client"
+			},
+			testNonce,
+		},
+		{"mismatched nonce", func(c map[string]interface{}) {}, "other-nonce"},
+		{"missing nonce", func(c map[string]interface{}) { delete(c, "nonce") }, testNonce},
+		{
+			"expired",
+			func(c map[string]interface{}) { c["exp"] = time.Now().Add(-leeway - time.Minute).Unix() },
+			testNonce,
+		},
+		{
+			"issued in the future",
+			func(c map[string]interface{}) { c["iat"] = time.Now().Add(leeway + time.Minute).Unix() },
+			testNonce,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := idp.provider()
+			c := idp.validClaims()
+			tt.modify(c)
+			idp.setToken(signES256(t, key, "ec-1", c))
+
+			if _, err := p.Exchange(context.Background(), "code", testVerifier, tt.nonce); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("err = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestExchangeAcceptsAuthorizedParty(t *testing.T) {
+	idp := newTestIdP(t)
+	key := idp.addECKey("ec-1")
+	p := idp.provider()
+
+	c := idp.validClaims()
+	c["aud"] = []string{testClientID, "other-client"}
+	c["azp"] = testClientID
+	idp.setToken(signES256(t, key, "ec-1", c))
+
+	if _, err := p.Exchange(context.Background(), "code", testVerifier, testNonce); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+}
+
+func TestExchangeRejectsSignatures(t *testing.T) {
+	idp := newTestIdP(t)
+	key := idp.addECKey("ec-1")
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := idp.validClaims()
+	payload := encodeSegment(t, claims)
+
+	hs256 := encodeSegment(t, jwtHeader{Alg: "HS256", Kid: "ec-1"}) + "." + payload
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(hs256))
+	hs256 += "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	valid := signES256(t, key, "ec-1", claims)
+	tampered := strings.Split(valid, ".")
+	tampered[1] = encodeSegment(t, map[string]interface{}{"sub": "admin"})
+
+	tests := map[string]string{
+		"alg none":       encodeSegment(t, jwtHeader{Alg: "none"}) + "." + payload + ".",
+		"alg HS256":      hs256,
+		"unknown key":    signES256(t, other, "ec-1", claims),
+		"tampered":       strings.Join(tampered, "."),
+		"not a JWT":      "not-a-jwt",
+		"bad base64":     "!!!.!!!.!!!",
+		"alg mismatched": encodeSegment(t, jwtHeader{Alg: "RS256", Kid: "ec-1"}) + "." + payload + ".AAAA",
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := idp.provider()
+			idp.setToken(token)
+
+			if _, err := p.Exchange(context.Background(), "code", testVerifier, testNonce); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("err = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestExchangeKeyRotation(t *testing.T) {
+	idp := newTestIdP(t)
+	oldKey := idp.addECKey("old")
+	p := idp.provider()
+	ctx := context.Background()
+
+	idp.setToken(signES256(t, oldKey, "old", idp.validClaims()))
+	if _, err := p.Exchange(ctx, "code", testVerifier, testNonce); err != nil {
+		t.Fatalf("Exchange with old key: %v", err)
+	}
+
+	newKey := idp.addECKey("new")
+	idp.setToken(signES256(t, newKey, "new", idp.validClaims()))
+
+	// A refetch happened moments ago, so the unknown key ID is rejected without another request.
+	if _, err := p.Exchange(ctx, "code", testVerifier, testNonce); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("err = %v, want ErrInvalidToken before the refresh interval", err)
+	}
+	if idp.fetches() != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", idp.fetches())
+	}
+
+	p.mu.Lock()
+	p.keysFetched = time.Now().Add(-jwksRefreshInterval)
+	p.mu.Unlock()
+
+	if _, err := p.Exchange(ctx, "code", testVerifier, testNonce); err != nil {
+		t.Fatalf("Exchange with rotated key: %v", err)
+	}
+	if idp.fetches() != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", idp.fetches())
+	}
+
+	// Known keys are served from the cache.
+	idp.setToken(signES256(t, oldKey, "old", idp.validClaims()))
+	if _, err := p.Exchange(ctx, "code", testVerifier, testNonce); err != nil {
+		t.Fatalf("Exchange with cached key: %v", err)
+	}
+	if idp.fetches() != 2 {
+		t.Errorf("jwks fetched %d times, want 2", idp.fetches())
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	idp := newTestIdP(t)
+	p := NewProvider(Config{Issuer: idp.server.URL + "/", ClientID: testClientID})
+
+	if _, err := p.AuthCodeURL(context.Background(), "state", "nonce", "challenge"); err == nil {
+		t.Fatal("AuthCodeURL succeeded with a mismatched discovery issuer")
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	idp := newTestIdP(t)
+	p := idp.provider()
+
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	if challenge != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		t.Fatal("code challenge isn't the S256 of the verifier")
+	}
+
+	raw, err := p.AuthCodeURL(context.Background(), "state-1", "nonce-1", challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("state") != "state-1" || q.Get("nonce") != "nonce-1" ||
+		q.Get("code_challenge") != challenge || q.Get("code_challenge_method") != "S256" ||
+		q.Get("client_id") != testClientID || q.Get("scope") != "openid email" {
+		t.Errorf("unexpected authorization URL %s", raw)
+	}
+}
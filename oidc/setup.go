@@ -0,0 +1,26 @@
+package oidc
+
+import (
+	"log"
+
+	"github.com/amiftachulh/notez-api/config"
+)
+
+// Default is the configured identity provider, or nil when single sign-on is disabled.
+var Default *Provider
+
+func Setup() {
+	if config.OIDCIssuer == "" {
+		return
+	}
+	if config.OIDCClientID == "" || config.OIDCRedirectURL == "" {
+		log.Fatalln("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER is set")
+	}
+	Default = NewProvider(Config{
+		Issuer:       config.OIDCIssuer,
+		ClientID:     config.OIDCClientID,
+		ClientSecret: config.OIDCClientSecret,
+		RedirectURL:  config.OIDCRedirectURL,
+		Scopes:       config.OIDCScopes,
+	})
+}
@@ -0,0 +1,313 @@
+// Package ot implements operational transformation for plain text documents.
+//
+// An operation is a list of components applied left to right over the whole document:
+// a positive number retains that many characters, a negative number deletes that many
+// characters and a string inserts it. Lengths are counted in Unicode code points.
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"unicode/utf8"
+)
+
+// MaxLength bounds the base and target length of decoded operations so counts sent by clients
+// can't overflow.
+const MaxLength = math.MaxInt32
+
+var (
+	ErrInvalidComponent = errors.New("ot: component must be a non-zero integer or a non-empty string")
+	ErrLengthMismatch   = errors.New("ot: operation base length does not match document length")
+	ErrIncompatible     = errors.New("ot: operations are not based on the same document")
+	ErrTooLong          = errors.New("ot: operation is longer than the maximum length")
+)
+
+type component struct {
+	n int
+	s string
+}
+
+func (c component) isRetain() bool { return c.n > 0 }
+func (c component) isDelete() bool { return c.n < 0 }
+func (c component) isInsert() bool { return c.s != "" }
+
+type Operation struct {
+	components   []component
+	BaseLength   int
+	TargetLength int
+}
+
+func (o *Operation) Retain(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.BaseLength += n
+	o.TargetLength += n
+	if last := len(o.components) - 1; last >= 0 && o.components[last].isRetain() {
+		o.components[last].n += n
+	} else {
+		o.components = append(o.components, component{n: n})
+	}
+	return o
+}
+
+func (o *Operation) Insert(s string) *Operation {
+	if s == "" {
+		return o
+	}
+	o.TargetLength += utf8.RuneCountInString(s)
+	last := len(o.components) - 1
+	switch {
+	case last >= 0 && o.components[last].isInsert():
+		o.components[last].s += s
+	case last >= 0 && o.components[last].isDelete():
+		// Keep inserts before deletes so equivalent operations have one representation.
+		if last > 0 && o.components[last-1].isInsert() {
+			o.components[last-1].s += s
+		} else {
+			o.components = append(o.components, o.components[last])
+			o.components[last] = component{s: s}
+		}
+	default:
+		o.components = append(o.components, component{s: s})
+	}
+	return o
+}
+
+func (o *Operation) Delete(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.BaseLength += n
+	if last := len(o.components) - 1; last >= 0 && o.components[last].isDelete() {
+		o.components[last].n -= n
+	} else {
+		o.components = append(o.components, component{n: -n})
+	}
+	return o
+}
+
+// IsNoop reports whether applying the operation leaves any document unchanged.
+func (o *Operation) IsNoop() bool {
+	return len(o.components) == 0 || (len(o.components) == 1 && o.components[0].isRetain())
+}
+
+func (o *Operation) Apply(doc string) (string, error) {
+	runes := []rune(doc)
+	if len(runes) != o.BaseLength {
+		return "", ErrLengthMismatch
+	}
+
+	// The lengths can't be trusted to match the components of an operation built with overflowing
+	// counts, so every component is checked against the document.
+	result := make([]rune, 0, min(o.TargetLength, len(runes)))
+	pos := 0
+	for _, c := range o.components {
+		switch {
+		case c.isRetain():
+			if c.n > len(runes)-pos {
+				return "", ErrLengthMismatch
+			}
+			result = append(result, runes[pos:pos+c.n]...)
+			pos += c.n
+		case c.isDelete():
+			if -c.n > len(runes)-pos {
+				return "", ErrLengthMismatch
+			}
+			pos -= c.n
+		default:
+			result = append(result, []rune(c.s)...)
+		}
+	}
+	if pos != len(runes) {
+		return "", ErrLengthMismatch
+	}
+	return string(result), nil
+}
+
+// Diff returns an operation turning a into b. Only the common prefix and suffix are kept, so
+// the result replaces everything in between as one change.
+func Diff(a, b string) *Operation {
+	ra, rb := []rune(a), []rune(b)
+
+	prefix := 0
+	for prefix < len(ra) && prefix < len(rb) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(ra)-prefix && suffix < len(rb)-prefix &&
+		ra[len(ra)-1-suffix] == rb[len(rb)-1-suffix] {
+		suffix++
+	}
+
+	op := &Operation{}
+	return op.
+		Retain(prefix).
+		Insert(string(rb[prefix : len(rb)-suffix])).
+		Delete(len(ra) - prefix - suffix).
+		Retain(suffix)
+}
+
+// Transform takes two operations a and b that apply to the same document and returns a' and b'
+// such that applying a then b' produces the same document as applying b then a'.
+// When both insert at the same position, a's insert is placed first.
+func Transform(a, b *Operation) (*Operation, *Operation, error) {
+	if a.BaseLength != b.BaseLength {
+		return nil, nil, ErrIncompatible
+	}
+
+	aPrime := &Operation{}
+	bPrime := &Operation{}
+	ops1, ops2 := a.components, b.components
+	i1, i2 := 0, 0
+	var op1, op2 *component
+	next := func(ops []component, i *int) *component {
+		if *i >= len(ops) {
+			return nil
+		}
+		c := ops[*i]
+		*i++
+		return &c
+	}
+	op1 = next(ops1, &i1)
+	op2 = next(ops2, &i2)
+
+	for op1 != nil || op2 != nil {
+		if op1 != nil && op1.isInsert() {
+			aPrime.Insert(op1.s)
+			bPrime.Retain(utf8.RuneCountInString(op1.s))
+			op1 = next(ops1, &i1)
+			continue
+		}
+		if op2 != nil && op2.isInsert() {
+			aPrime.Retain(utf8.RuneCountInString(op2.s))
+			bPrime.Insert(op2.s)
+			op2 = next(ops2, &i2)
+			continue
+		}
+		if op1 == nil || op2 == nil {
+			return nil, nil, ErrIncompatible
+		}
+
+		switch {
+		case op1.isRetain() && op2.isRetain():
+			var minl int
+			switch {
+			case op1.n > op2.n:
+				minl = op2.n
+				op1.n -= op2.n
+				op2 = next(ops2, &i2)
+			case op1.n == op2.n:
+				minl = op2.n
+				op1 = next(ops1, &i1)
+				op2 = next(ops2, &i2)
+			default:
+				minl = op1.n
+				op2.n -= op1.n
+				op1 = next(ops1, &i1)
+			}
+			aPrime.Retain(minl)
+			bPrime.Retain(minl)
+		case op1.isDelete() && op2.isDelete():
+			switch {
+			case -op1.n > -op2.n:
+				op1.n -= op2.n
+				op2 = next(ops2, &i2)
+			case op1.n == op2.n:
+				op1 = next(ops1, &i1)
+				op2 = next(ops2, &i2)
+			default:
+				op2.n -= op1.n
+				op1 = next(ops1, &i1)
+			}
+		case op1.isDelete() && op2.isRetain():
+			var minl int
+			switch {
+			case -op1.n > op2.n:
+				minl = op2.n
+				op1.n += op2.n
+				op2 = next(ops2, &i2)
+			case -op1.n == op2.n:
+				minl = op2.n
+				op1 = next(ops1, &i1)
+				op2 = next(ops2, &i2)
+			default:
+				minl = -op1.n
+				op2.n += op1.n
+				op1 = next(ops1, &i1)
+			}
+			aPrime.Delete(minl)
+		default:
+			var minl int
+			switch {
+			case op1.n > -op2.n:
+				minl = -op2.n
+				op1.n += op2.n
+				op2 = next(ops2, &i2)
+			case op1.n == -op2.n:
+				minl = op1.n
+				op1 = next(ops1, &i1)
+				op2 = next(ops2, &i2)
+			default:
+				minl = op1.n
+				op2.n += op1.n
+				op1 = next(ops1, &i1)
+			}
+			bPrime.Delete(minl)
+		}
+	}
+
+	return aPrime, bPrime, nil
+}
+
+func (o Operation) MarshalJSON() ([]byte, error) {
+	raw := make([]interface{}, len(o.components))
+	for i, c := range o.components {
+		if c.isInsert() {
+			raw[i] = c.s
+		} else {
+			raw[i] = c.n
+		}
+	}
+	return json.Marshal(raw)
+}
+
+func (o *Operation) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*o = Operation{}
+	for _, r := range raw {
+		var n int
+		if err := json.Unmarshal(r, &n); err == nil {
+			switch {
+			case n > 0:
+				if n > MaxLength-o.BaseLength || n > MaxLength-o.TargetLength {
+					return ErrTooLong
+				}
+				o.Retain(n)
+			case n < 0:
+				if n < o.BaseLength-MaxLength {
+					return ErrTooLong
+				}
+				o.Delete(-n)
+			default:
+				return ErrInvalidComponent
+			}
+			continue
+		}
+
+		var s string
+		if err := json.Unmarshal(r, &s); err != nil || s == "" {
+			return ErrInvalidComponent
+		}
+		if utf8.RuneCountInString(s) > MaxLength-o.TargetLength {
+			return ErrTooLong
+		}
+		o.Insert(s)
+	}
+	return nil
+}
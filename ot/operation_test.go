@@ -0,0 +1,195 @@
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func parseOp(t *testing.T, s string) *Operation {
+	t.Helper()
+	var op Operation
+	if err := json.Unmarshal([]byte(s), &op); err != nil {
+		t.Fatalf("unmarshal %s: %v", s, err)
+	}
+	return &op
+}
+
+func mustApply(t *testing.T, op *Operation, doc string) string {
+	t.Helper()
+	result, err := op.Apply(doc)
+	if err != nil {
+		t.Fatalf("apply %s to %q: %v", mustMarshal(t, op), doc, err)
+	}
+	return result
+}
+
+func mustMarshal(t *testing.T, op *Operation) string {
+	t.Helper()
+	data, err := json.Marshal(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		doc  string
+		op   string
+		want string
+	}{
+		{"hello", `[5," world"]`, "hello world"},
+		{"hello", `["oh, ",5]`, "oh, hello"},
+		{"hello", `[1,-3,1]`, "ho"},
+		{"hello", `[-5]`, ""},
+		{"", `["hi"]`, "hi"},
+		{"héllo", `[1,"é",-1,3]`, "héllo"},
+		{"日本語", `[1,"🙂",-1,1]`, "日🙂語"},
+		{"a🙂b", `[2,-1,"c"]`, "a🙂c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			if got := mustApply(t, parseOp(t, tt.op), tt.doc); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyRejectsMismatchedComponents(t *testing.T) {
+	// Operations built directly can disagree with their lengths, so Apply checks each component.
+	tests := map[string]*Operation{
+		"retain past end": {components: []component{{n: 6}}, BaseLength: 5},
+		"delete past end": {components: []component{{n: 2}, {n: -4}}, BaseLength: 5},
+		"short":           {components: []component{{n: 3}}, BaseLength: 5},
+		"base length":     (&Operation{}).Retain(4),
+	}
+
+	for name, op := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := op.Apply("hello"); !errors.Is(err, ErrLengthMismatch) {
+				t.Errorf("err = %v, want ErrLengthMismatch", err)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"", ""},
+		{"", "hello"},
+		{"hello", ""},
+		{"hello", "hello"},
+		{"hello", "help"},
+		{"hello world", "hello there world"},
+		{"héllo", "hello"},
+		{"日本語", "日本の語"},
+		{"🙂🙃", "🙃🙂"},
+		{"a🙂b", "a🙃b"},
+		{"aaa", "aaaa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"→"+tt.b, func(t *testing.T) {
+			op := Diff(tt.a, tt.b)
+			if got := mustApply(t, op, tt.a); got != tt.b {
+				t.Errorf("got %q, want %q", got, tt.b)
+			}
+
+			// The diff survives the trip through JSON.
+			if got := mustApply(t, parseOp(t, mustMarshal(t, op)), tt.a); got != tt.b {
+				t.Errorf("after JSON round-trip got %q, want %q", got, tt.b)
+			}
+		})
+	}
+}
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		a, b string
+	}{
+		{"both insert at the same position", "hello", `[5,"!"]`, `[5,"?"]`},
+		{"inserts at different positions", "hello", `["<",5]`, `[5,">"]`},
+		{"insert inside a delete", "hello world", `[3,"p",8]`, `[1,-8,2]`},
+		{"overlapping deletes", "hello world", `[2,-5,4]`, `[4,-5,2]`},
+		{"same delete", "hello", `[1,-3,1]`, `[1,-3,1]`},
+		{"delete everything and insert", "hello", `[-5]`, `[2,"xx",3]`},
+		{"replace and retain", "abc", `["x",-3]`, `[3]`},
+		{"multi-byte", "日本語🙂", `[1,"の",-1,2]`, `[3,-1,"🙃"]`},
+		{"empty document", "", `["a"]`, `["b"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := parseOp(t, tt.a), parseOp(t, tt.b)
+			aPrime, bPrime, err := Transform(a, b)
+			if err != nil {
+				t.Fatalf("transform: %v", err)
+			}
+
+			ab := mustApply(t, bPrime, mustApply(t, a, tt.doc))
+			ba := mustApply(t, aPrime, mustApply(t, b, tt.doc))
+			if ab != ba {
+				t.Errorf("a then b' = %q, b then a' = %q", ab, ba)
+			}
+		})
+	}
+}
+
+func TestTransformIncompatible(t *testing.T) {
+	a := (&Operation{}).Retain(3)
+	b := (&Operation{}).Retain(4)
+	if _, _, err := Transform(a, b); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("err = %v, want ErrIncompatible", err)
+	}
+}
+
+func TestUnmarshalJSONRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want error
+	}{
+		{"zero", `[0]`, ErrInvalidComponent},
+		{"empty insert", `[""]`, ErrInvalidComponent},
+		{"float", `[1.5]`, ErrInvalidComponent},
+		{"object", `[{}]`, ErrInvalidComponent},
+		{"null", `[null]`, ErrInvalidComponent},
+		{"retain overflow", `[9223372036854775807,"x",9223372036854775807,"y",7]`, ErrTooLong},
+		{"delete overflow", `[-9223372036854775808]`, ErrTooLong},
+		{"retains add past max", `[2147483647,1]`, ErrTooLong},
+		{"deletes add past max", `[-2147483647,-1]`, ErrTooLong},
+		{"retain after delete past max", `[-2147483647,1]`, ErrTooLong},
+		{"insert past max", `[2147483647,"x"]`, ErrTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var op Operation
+			if err := json.Unmarshal([]byte(tt.json), &op); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	var op Operation
+	for _, s := range []string{`{}`, `"x"`, `[1,`} {
+		if err := json.Unmarshal([]byte(s), &op); err == nil {
+			t.Errorf("%s: no error", s)
+		}
+	}
+}
+
+func TestUnmarshalJSONAtMaxLength(t *testing.T) {
+	op := parseOp(t, `[2147483646,-1]`)
+	if op.BaseLength != MaxLength || op.TargetLength != MaxLength-1 {
+		t.Errorf("lengths = %d, %d", op.BaseLength, op.TargetLength)
+	}
+	if _, err := op.Apply("hello"); !errors.Is(err, ErrLengthMismatch) {
+		t.Errorf("err = %v, want ErrLengthMismatch", err)
+	}
+}
@@ -9,62 +9,466 @@ import (
 )
 
 func Setup(app *fiber.App) {
+	public := app.Group("/public")
+	public.Get("/:slug", middleware.ValidateParams(&model.PublicNoteParams{}), handler.GetPublicNote)
+	public.Post("/:slug", middleware.ValidateParams(&model.PublicNoteParams{}), handler.GetPublicNote)
+
 	v1 := app.Group("/v1")
 
 	auth := v1.Group("/auth")
 	auth.Post("/register", middleware.ValidateBody(&model.Register{}), handler.Register)
 	auth.Post("/login", middleware.ValidateBody(&model.Login{}), handler.Login)
-	auth.Post("/logout", handler.Logout)
+	auth.Post("/logout", middleware.Authenticate, middleware.VerifyCSRF, handler.Logout)
+	auth.Post(
+		"/login/2fa",
+		middleware.ValidateBody(&model.LoginTwoFactor{}),
+		handler.LoginTwoFactor,
+	)
+	auth.Post(
+		"/unlock",
+		middleware.ValidateBody(&model.UnlockAccount{}),
+		handler.UnlockAccount,
+	)
 	auth.Get("/check", handler.CheckAuth)
+	auth.Get(
+		"/oidc/login",
+		middleware.ValidateQuery(&model.OIDCLoginQuery{}),
+		handler.OIDCLogin,
+	)
+	auth.Get(
+		"/oidc/callback",
+		middleware.ValidateQuery(&model.OIDCCallbackQuery{}),
+		handler.OIDCCallback,
+	)
+	auth.Post(
+		"/webauthn/register/begin",
+		middleware.Authenticate,
+		middleware.VerifyCSRF,
+		handler.BeginPasskeyRegistration,
+	)
+	auth.Post(
+		"/webauthn/register/finish",
+		middleware.Authenticate,
+		middleware.VerifyCSRF,
+		middleware.ValidateBody(&model.PasskeyRegistration{}),
+		handler.FinishPasskeyRegistration,
+	)
+	auth.Post(
+		"/webauthn/login/begin",
+		middleware.ValidateBody(&model.PasskeyLoginBegin{}),
+		handler.BeginPasskeyLogin,
+	)
+	auth.Post(
+		"/webauthn/login/finish",
+		middleware.ValidateBody(&model.PasskeyLogin{}),
+		handler.FinishPasskeyLogin,
+	)
+	auth.Post(
+		"/verify-email",
+		middleware.ValidateBody(&model.VerifyEmail{}),
+		handler.VerifyEmail,
+	)
+	auth.Post(
+		"/forgot-password",
+		middleware.ValidateBody(&model.ForgotPassword{}),
+		handler.ForgotPassword,
+	)
+	auth.Post(
+		"/reset-password",
+		middleware.ValidateBody(&model.ResetPassword{}),
+		handler.ResetPassword,
+	)
 
-	protected := v1.Group("/").Use(middleware.Authenticate)
+	protected := v1.Group("/").Use(middleware.Authenticate, middleware.VerifyCSRF)
 
-	profile := protected.Group("/profile")
+	profile := protected.Group("/profile", middleware.RequireSession)
 	profile.Patch("/", middleware.ValidateBody(&model.UpdateUserInfo{}), handler.UpdateUserInfo)
+	profile.Delete("/", middleware.ValidateBody(&model.DeleteAccount{}), handler.DeleteProfile)
+	profile.Get("/export", handler.ExportProfile)
 	profile.Patch(
 		"/email",
 		middleware.ValidateBody(&model.UpdateUserEmail{}),
 		handler.UpdateUserEmail,
 	)
+	profile.Post("/email/verification", handler.ResendEmailVerification)
 	profile.Patch(
 		"/password",
 		middleware.ValidateBody(&model.UpdateUserPassword{}),
 		handler.UpdateUserPassword,
 	)
+	profile.Post("/2fa/setup", handler.SetupTwoFactor)
+	profile.Post(
+		"/2fa/confirm",
+		middleware.ValidateBody(&model.TwoFactorCode{}),
+		handler.ConfirmTwoFactor,
+	)
+	profile.Post(
+		"/2fa/disable",
+		middleware.ValidateBody(&model.TwoFactorPassword{}),
+		handler.DisableTwoFactor,
+	)
+	profile.Post(
+		"/2fa/recovery-codes",
+		middleware.ValidateBody(&model.TwoFactorPassword{}),
+		handler.RegenerateRecoveryCodes,
+	)
+	profile.Get("/passkeys", handler.GetPasskeys)
+	profile.Patch(
+		"/passkeys/:id",
+		middleware.ValidateParams(&model.PasskeyParams{}),
+		middleware.ValidateBody(&model.PasskeyInput{}),
+		handler.RenamePasskey,
+	)
+	profile.Delete(
+		"/passkeys/:id",
+		middleware.ValidateParams(&model.PasskeyParams{}),
+		handler.DeletePasskey,
+	)
+
+	sessions := protected.Group("/sessions", middleware.RequireSession)
+	sessions.Get("/", handler.GetSessions)
+	sessions.Delete("/", handler.RevokeOtherSessions)
+	sessions.Delete(
+		"/:id",
+		middleware.ValidateParams(&model.SessionParams{}),
+		handler.RevokeSession,
+	)
+
+	tokens := protected.Group("/tokens", middleware.RequireSession)
+	tokens.Post("/", middleware.ValidateBody(&model.APITokenInput{}), handler.CreateAPIToken)
+	tokens.Get("/", handler.GetAPITokens)
+	tokens.Delete(
+		"/:id",
+		middleware.ValidateParams(&model.APITokenParams{}),
+		handler.RevokeAPIToken,
+	)
+
+	admin := protected.Group("/admin", middleware.RequireSession, middleware.RequireAdmin)
+	admin.Get("/users", middleware.ValidateQuery(&model.AdminUserQuery{}), handler.AdminGetUsers)
+	admin.Get(
+		"/users/:id",
+		middleware.ValidateParams(&model.AdminUserParams{}),
+		handler.AdminGetUser,
+	)
+	admin.Patch(
+		"/users/:id/role",
+		middleware.ValidateParams(&model.AdminUserParams{}),
+		middleware.ValidateBody(&model.UpdateUserRole{}),
+		handler.AdminUpdateUserRole,
+	)
+	admin.Post(
+		"/users/:id/disable",
+		middleware.ValidateParams(&model.AdminUserParams{}),
+		middleware.ValidateBody(&model.AdminDisableUser{}),
+		handler.AdminDisableUser,
+	)
+	admin.Post(
+		"/users/:id/enable",
+		middleware.ValidateParams(&model.AdminUserParams{}),
+		handler.AdminEnableUser,
+	)
+	admin.Post(
+		"/users/:id/password-reset",
+		middleware.ValidateParams(&model.AdminUserParams{}),
+		handler.AdminForcePasswordReset,
+	)
+	admin.Post(
+		"/users/:id/unlock",
+		middleware.ValidateParams(&model.AdminUserParams{}),
+		handler.AdminUnlockUser,
+	)
+	admin.Delete(
+		"/users/:id",
+		middleware.ValidateParams(&model.AdminUserParams{}),
+		handler.AdminDeleteUser,
+	)
 
 	notes := protected.Group("/notes")
-	notes.Post("/", middleware.ValidateBody(&model.NoteInput{}), handler.CreateNote)
-	notes.Get("/", middleware.ValidateQuery(&model.NoteQuery{}), handler.GetNotes)
-	notes.Get("/:id", middleware.ValidateParams(&model.NoteParams{}), handler.GetNoteByID)
+	notes.Post(
+		"/",
+		middleware.RequireScope("notes:write"),
+		middleware.ValidateBody(&model.NoteInput{}),
+		handler.CreateNote,
+	)
+	notes.Get(
+		"/",
+		middleware.RequireScope("notes:read"),
+		middleware.ValidateQuery(&model.NoteQuery{}),
+		handler.GetNotes,
+	)
+	notes.Get(
+		"/:id",
+		middleware.RequireScope("notes:read"),
+		middleware.ValidateParams(&model.NoteParams{}),
+		handler.GetNoteByID,
+	)
 	notes.Put(
 		"/:id",
+		middleware.RequireScope("notes:write"),
 		middleware.ValidateParams(&model.NoteParams{}),
 		middleware.ValidateBody(&model.NoteInput{}),
 		handler.UpdateNoteByID,
 	)
-	notes.Delete("/:id", middleware.ValidateParams(&model.NoteParams{}), handler.DeleteNoteByID)
+	notes.Delete(
+		"/:id",
+		middleware.RequireScope("notes:write"),
+		middleware.ValidateParams(&model.NoteParams{}),
+		handler.DeleteNoteByID,
+	)
+	notes.Post(
+		"/:id/restore",
+		middleware.RequireScope("notes:write"),
+		middleware.ValidateParams(&model.NoteParams{}),
+		handler.RestoreNote,
+	)
+	notes.Get(
+		"/:id/revisions",
+		middleware.RequireScope("notes:read"),
+		middleware.ValidateParams(&model.NoteParams{}),
+		handler.GetNoteRevisions,
+	)
+	notes.Get(
+		"/:id/revisions/:rev",
+		middleware.RequireScope("notes:read"),
+		middleware.ValidateParams(&model.NoteRevisionParams{}),
+		handler.GetNoteRevisionByID,
+	)
+	notes.Post(
+		"/:id/revisions/:rev/restore",
+		middleware.RequireScope("notes:write"),
+		middleware.ValidateParams(&model.NoteRevisionParams{}),
+		handler.RestoreNoteRevision,
+	)
+	// The live connection accepts edits, so tokens need the write scope to open it.
+	notes.Get(
+		"/:id/live",
+		middleware.RequireScope("notes:write"),
+		middleware.ValidateParams(&model.NoteParams{}),
+		handler.UpgradeNoteLive,
+		handler.NoteLive,
+	)
+	notes.Patch(
+		"/:id/state",
+		middleware.RequireScope("notes:write"),
+		middleware.ValidateParams(&model.NoteParams{}),
+		middleware.ValidateBody(&model.UpdateNoteState{}),
+		handler.UpdateNoteState,
+	)
+	notes.Put(
+		"/:id/notebook",
+		middleware.RequireScope("notes:write"),
+		middleware.ValidateParams(&model.NoteParams{}),
+		middleware.ValidateBody(&model.MoveNote{}),
+		handler.MoveNote,
+	)
+	notes.Put(
+		"/:id/tags/:tagID",
+		middleware.RequireScope("notes:write"),
+		middleware.ValidateParams(&model.NoteTagParams{}),
+		handler.AttachNoteTag,
+	)
+	notes.Delete(
+		"/:id/tags/:tagID",
+		middleware.RequireScope("notes:write"),
+		middleware.ValidateParams(&model.NoteTagParams{}),
+		handler.DetachNoteTag,
+	)
+	notes.Post(
+		"/:id/links",
+		middleware.RequireSession,
+		middleware.ValidateParams(&model.NoteParams{}),
+		middleware.RequireVerifiedEmail,
+		middleware.ValidateBody(&model.NoteJoinLinkInput{}),
+		handler.CreateNoteJoinLink,
+	)
+	notes.Get(
+		"/:id/links",
+		middleware.RequireSession,
+		middleware.ValidateParams(&model.NoteParams{}),
+		handler.GetNoteJoinLinks,
+	)
+	notes.Delete(
+		"/:id/links/:linkID",
+		middleware.RequireSession,
+		middleware.ValidateParams(&model.NoteJoinLinkParams{}),
+		handler.RevokeNoteJoinLink,
+	)
+	notes.Put(
+		"/:id/publication",
+		middleware.RequireSession,
+		middleware.ValidateParams(&model.NoteParams{}),
+		middleware.RequireVerifiedEmail,
+		middleware.ValidateBody(&model.PublishNote{}),
+		handler.PublishNote,
+	)
+	notes.Get(
+		"/:id/publication",
+		middleware.RequireSession,
+		middleware.ValidateParams(&model.NoteParams{}),
+		handler.GetNotePublication,
+	)
+	notes.Delete(
+		"/:id/publication",
+		middleware.RequireSession,
+		middleware.ValidateParams(&model.NoteParams{}),
+		handler.UnpublishNote,
+	)
+	notes.Post(
+		"/:id/transfer",
+		middleware.RequireSession,
+		middleware.ValidateParams(&model.NoteParams{}),
+		middleware.ValidateBody(&model.CreateNoteTransfer{}),
+		handler.TransferNote,
+	)
 	notes.Patch(
 		"/:id/members/:memberID",
+		middleware.RequireScope("notes:write"),
 		middleware.ValidateParams(&model.NoteMemberParams{}),
 		middleware.ValidateBody(&model.UpdateNoteMemberRole{}),
 		handler.UpdateNoteMemberRole,
 	)
 	notes.Delete(
 		"/:id/members/:memberID",
+		middleware.RequireScope("notes:write"),
 		middleware.ValidateParams(&model.NoteMemberParams{}),
 		handler.RemoveNoteMember,
 	)
 
-	noteInvitation := protected.Group("/note-invitations")
+	trash := protected.Group("/trash")
+	trash.Get(
+		"/",
+		middleware.RequireScope("notes:read"),
+		middleware.ValidateQuery(&model.TrashQuery{}),
+		handler.GetTrash,
+	)
+	trash.Delete("/", middleware.RequireScope("notes:write"), handler.EmptyTrash)
+	trash.Delete(
+		"/:id",
+		middleware.RequireScope("notes:write"),
+		middleware.ValidateParams(&model.NoteParams{}),
+		handler.DeleteTrashedNote,
+	)
+
+	notebooks := protected.Group("/notebooks")
+	notebooks.Post(
+		"/",
+		middleware.RequireScope("notebooks:write"),
+		middleware.ValidateBody(&model.NotebookInput{}),
+		handler.CreateNotebook,
+	)
+	notebooks.Get("/", middleware.RequireScope("notebooks:read"), handler.GetNotebooks)
+	notebooks.Get(
+		"/:id",
+		middleware.RequireScope("notebooks:read"),
+		middleware.ValidateParams(&model.NotebookParams{}),
+		handler.GetNotebookByID,
+	)
+	notebooks.Put(
+		"/:id",
+		middleware.RequireScope("notebooks:write"),
+		middleware.ValidateParams(&model.NotebookParams{}),
+		middleware.ValidateBody(&model.NotebookInput{}),
+		handler.UpdateNotebook,
+	)
+	notebooks.Delete(
+		"/:id",
+		middleware.RequireScope("notebooks:write"),
+		middleware.ValidateParams(&model.NotebookParams{}),
+		handler.DeleteNotebook,
+	)
+	notebooks.Post(
+		"/:id/members",
+		middleware.RequireScope("notebooks:write"),
+		middleware.ValidateParams(&model.NotebookParams{}),
+		middleware.RequireVerifiedEmail,
+		middleware.ValidateBody(&model.AddNotebookMember{}),
+		handler.AddNotebookMember,
+	)
+	notebooks.Patch(
+		"/:id/members/:memberID",
+		middleware.RequireScope("notebooks:write"),
+		middleware.ValidateParams(&model.NotebookMemberParams{}),
+		middleware.ValidateBody(&model.UpdateNoteMemberRole{}),
+		handler.UpdateNotebookMemberRole,
+	)
+	notebooks.Delete(
+		"/:id/members/:memberID",
+		middleware.RequireScope("notebooks:write"),
+		middleware.ValidateParams(&model.NotebookMemberParams{}),
+		handler.RemoveNotebookMember,
+	)
+
+	tags := protected.Group("/tags")
+	tags.Post(
+		"/",
+		middleware.RequireScope("tags:write"),
+		middleware.ValidateBody(&model.TagInput{}),
+		handler.CreateTag,
+	)
+	tags.Get("/", middleware.RequireScope("tags:read"), handler.GetTags)
+	tags.Patch(
+		"/:id",
+		middleware.RequireScope("tags:write"),
+		middleware.ValidateParams(&model.TagParams{}),
+		middleware.ValidateBody(&model.TagInput{}),
+		handler.RenameTag,
+	)
+	tags.Delete(
+		"/:id",
+		middleware.RequireScope("tags:write"),
+		middleware.ValidateParams(&model.TagParams{}),
+		handler.DeleteTag,
+	)
+
+	protected.Post(
+		"/join/:token",
+		middleware.RequireSession,
+		middleware.ValidateParams(&model.JoinParams{}),
+		handler.JoinNote,
+	)
+
+	noteInvitation := protected.Group("/note-invitations", middleware.RequireSession)
 	noteInvitation.Post(
 		"/",
+		middleware.RequireVerifiedEmail,
 		middleware.ValidateBody(&model.CreateNoteInvitation{}),
 		handler.CreateNoteInvitation,
 	)
 	noteInvitation.Get("/", handler.GetNoteInvitations)
+	noteInvitation.Get("/sent", handler.GetSentNoteInvitations)
+	noteInvitation.Get("/pending", handler.GetPendingNoteInvitations)
+	noteInvitation.Post(
+		"/pending/:id/resend",
+		middleware.ValidateParams(&model.NoteInvitationParams{}),
+		handler.ResendPendingNoteInvitation,
+	)
+	noteInvitation.Delete(
+		"/pending/:id",
+		middleware.ValidateParams(&model.NoteInvitationParams{}),
+		handler.DeletePendingNoteInvitation,
+	)
 	noteInvitation.Patch(
 		"/:id",
 		middleware.ValidateParams(&model.NoteInvitationParams{}),
 		handler.RespondNoteInvitation,
 	)
+	noteInvitation.Delete(
+		"/:id",
+		middleware.ValidateParams(&model.NoteInvitationParams{}),
+		handler.RevokeNoteInvitation,
+	)
+
+	noteTransfer := protected.Group("/note-transfers", middleware.RequireSession)
+	noteTransfer.Get("/", handler.GetNoteTransfers)
+	noteTransfer.Get("/sent", handler.GetSentNoteTransfers)
+	noteTransfer.Patch(
+		"/:id",
+		middleware.ValidateParams(&model.NoteTransferParams{}),
+		handler.RespondNoteTransfer,
+	)
+	noteTransfer.Delete(
+		"/:id",
+		middleware.ValidateParams(&model.NoteTransferParams{}),
+		handler.CancelNoteTransfer,
+	)
 }
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+// GetUserExport collects everything the user owns or takes part in, trashed notes included.
+func GetUserExport(userID uuid.UUID) (*model.UserExport, error) {
+	export := model.UserExport{
+		ExportedAt:  time.Now().UTC().Format(time.RFC3339),
+		Notes:       []model.ExportNote{},
+		Memberships: []model.ExportMembership{},
+		Invitations: []model.ExportInvitation{},
+	}
+
+	query := "SELECT id, name, email, role, created_at, updated_at FROM users WHERE id = $1"
+	if err := db.DB.
+		QueryRow(query, userID).
+		Scan(
+			&export.Profile.ID,
+			&export.Profile.Name,
+			&export.Profile.Email,
+			&export.Profile.Role,
+			&export.Profile.CreatedAt,
+			&export.Profile.UpdatedAt,
+		); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	query = `
+		SELECT n.id, n.notebook_id, n.title, n.content, ` + noteTagsColumn("$1") + `,
+			n.created_at, n.updated_at, n.deleted_at
+		FROM notes n
+		WHERE n.user_id = $1
+		ORDER BY n.created_at
+	`
+	rows, err := db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var n model.ExportNote
+		if err := rows.Scan(
+			&n.ID,
+			&n.NotebookID,
+			&n.Title,
+			&n.Content,
+			&n.Tags,
+			&n.CreatedAt,
+			&n.UpdatedAt,
+			&n.DeletedAt,
+		); err != nil {
+			log.Println(err)
+		}
+		export.Notes = append(export.Notes, n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT n.id, n.title, u.id, u.email, u.name, a.role, n.created_at
+		FROM note_access a
+		JOIN notes n ON a.note_id = n.id
+		JOIN users u ON n.user_id = u.id
+		WHERE a.user_id = $1 AND n.user_id <> $1 AND n.deleted_at IS NULL
+		ORDER BY n.created_at
+	`
+	rows, err = db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var m model.ExportMembership
+		if err := rows.Scan(
+			&m.NoteID,
+			&m.Title,
+			&m.Owner.ID,
+			&m.Owner.Email,
+			&m.Owner.Name,
+			&m.Role,
+			&m.CreatedAt,
+		); err != nil {
+			log.Println(err)
+		}
+		export.Memberships = append(export.Memberships, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT ni.id, n.id, n.title, 'received', i.id, i.email, i.name, ni.role,
+			` + invitationStatusColumn + `, ni.created_at
+		FROM note_invitations ni
+		JOIN notes n ON ni.note_id = n.id
+		JOIN users i ON ni.inviter_id = i.id
+		WHERE ni.user_id = $1
+		UNION ALL
+		SELECT ni.id, n.id, n.title, 'sent', u.id, u.email, u.name, ni.role,
+			` + invitationStatusColumn + `, ni.created_at
+		FROM note_invitations ni
+		JOIN notes n ON ni.note_id = n.id
+		JOIN users u ON ni.user_id = u.id
+		WHERE ni.inviter_id = $1
+		ORDER BY 10
+	`
+	rows, err = db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var i model.ExportInvitation
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.Title,
+			&i.Direction,
+			&i.User.ID,
+			&i.User.Email,
+			&i.User.Name,
+			&i.Role,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			log.Println(err)
+		}
+		export.Invitations = append(export.Invitations, i)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// DeleteAccount removes the user and everything that cascades from it. When transferShared is
+// set, owned notes with direct members are first handed over so the members keep them.
+func DeleteAccount(userID uuid.UUID, transferShared bool) (int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	var transferred int
+	if transferShared {
+		if transferred, err = transferNotesToHeirs(tx, userID); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err = tx.Exec("DELETE FROM users WHERE id = $1", userID); err != nil {
+		return 0, err
+	}
+
+	return transferred, tx.Commit()
+}
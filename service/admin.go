@@ -0,0 +1,283 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+const adminUserColumns = `
+	id, name, email, role, email_verified_at, totp_enabled_at IS NOT NULL, disabled_at,
+	password_reset_required, created_at, updated_at
+`
+
+func scanAdminUser(scanner interface{ Scan(...interface{}) error }, u *model.AdminUser) error {
+	return scanner.Scan(
+		&u.ID,
+		&u.Name,
+		&u.Email,
+		&u.Role,
+		&u.EmailVerifiedAt,
+		&u.TwoFactorEnabled,
+		&u.DisabledAt,
+		&u.PasswordResetRequired,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	)
+}
+
+func GetAdminUsers(opts *model.AdminUserQuery) ([]model.AdminUser, int, error) {
+	users := []model.AdminUser{}
+
+	whereBuilder := strings.Builder{}
+	whereBuilder.WriteString(" WHERE TRUE")
+	params := []interface{}{}
+
+	if opts.Query != "" {
+		whereBuilder.WriteString(
+			fmt.Sprintf(" AND (email ILIKE $%d OR name ILIKE $%d)", len(params)+1, len(params)+1),
+		)
+		params = append(params, "%"+opts.Query+"%")
+	}
+	if opts.Role != "" {
+		whereBuilder.WriteString(fmt.Sprintf(" AND role = $%d", len(params)+1))
+		params = append(params, opts.Role)
+	}
+	if opts.Status == "active" {
+		whereBuilder.WriteString(" AND disabled_at IS NULL")
+	} else if opts.Status == "disabled" {
+		whereBuilder.WriteString(" AND disabled_at IS NOT NULL")
+	}
+
+	where := whereBuilder.String()
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM users%s ORDER BY created_at DESC LIMIT $%d OFFSET $%d",
+		adminUserColumns,
+		where,
+		len(params)+1,
+		len(params)+2,
+	)
+	rows, err := db.DB.Query(
+		query,
+		append(params, opts.PageSize, (opts.Page-1)*opts.PageSize)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var u model.AdminUser
+		if err := scanAdminUser(rows, &u); err != nil {
+			log.Println(err)
+		}
+		users = append(users, u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	query = "SELECT COUNT(*) FROM users" + where
+	if err = db.DB.QueryRow(query, params...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func GetAdminUserByID(userID uuid.UUID) (*model.AdminUserDetail, error) {
+	var u model.AdminUserDetail
+	query := "SELECT " + adminUserColumns + " FROM users WHERE id = $1"
+	if err := scanAdminUser(db.DB.QueryRow(query, userID), &u.AdminUser); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	query = `
+		SELECT
+			COUNT(*) FILTER (WHERE deleted_at IS NULL),
+			(
+				SELECT COUNT(DISTINCT a.note_id)
+				FROM note_access a
+				JOIN notes n
+				ON a.note_id = n.id
+				WHERE a.user_id = $1 AND n.deleted_at IS NULL
+			),
+			COUNT(*) FILTER (WHERE deleted_at IS NOT NULL)
+		FROM notes
+		WHERE user_id = $1
+	`
+	if err := db.DB.
+		QueryRow(query, userID).
+		Scan(&u.NoteCounts.Owned, &u.NoteCounts.Shared, &u.NoteCounts.Trashed); err != nil {
+		return nil, err
+	}
+
+	sessions, err := GetSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	u.Sessions = sessions
+
+	return &u, nil
+}
+
+// SetUserDisabled disables or re-enables the user. Disabling signs the user out everywhere, and
+// their API tokens stop working for as long as the account stays disabled.
+func SetUserDisabled(userID uuid.UUID, disabled bool) (bool, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	result, err := setUserDisabled(tx, userID, disabled)
+	if err != nil || !result {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// DisableUser disables the user like SetUserDisabled and, when transfer is set, requests the
+// transfer of their notes in the same transaction. It returns how many transfers were requested.
+func DisableUser(
+	userID uuid.UUID,
+	transfer bool,
+	transferTo *uuid.UUID,
+	expiresAt time.Time,
+) (bool, int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, 0, err
+	}
+
+	defer tx.Rollback()
+
+	result, err := setUserDisabled(tx, userID, true)
+	if err != nil || !result {
+		return false, 0, err
+	}
+
+	transfers := 0
+	if transfer {
+		if transfers, err = requestBulkNoteTransfer(tx, userID, transferTo, expiresAt); err != nil {
+			return false, 0, err
+		}
+	}
+
+	return true, transfers, tx.Commit()
+}
+
+func setUserDisabled(tx *sql.Tx, userID uuid.UUID, disabled bool) (bool, error) {
+	query := "UPDATE users SET disabled_at = NULL WHERE id = $1"
+	if disabled {
+		query = "UPDATE users SET disabled_at = COALESCE(disabled_at, NOW()) WHERE id = $1"
+	}
+	result, err := tx.Exec(query, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if disabled {
+		if _, err = tx.Exec("DELETE FROM sessions WHERE user_id = $1", userID); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// RequirePasswordReset blocks password sign-in until the user resets their password, signs them
+// out everywhere and revokes their API tokens.
+func RequirePasswordReset(userID uuid.UUID) (bool, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	query := "UPDATE users SET password_reset_required = TRUE WHERE id = $1"
+	result, err := tx.Exec(query, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if _, err = tx.Exec("DELETE FROM sessions WHERE user_id = $1", userID); err != nil {
+		return false, err
+	}
+	if _, err = tx.Exec("DELETE FROM api_tokens WHERE user_id = $1", userID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func UpdateUserRole(userID uuid.UUID, role string) (bool, error) {
+	query := "UPDATE users SET role = $1 WHERE id = $2"
+	result, err := db.DB.Exec(query, role, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// DeleteUser deletes the user after handing each of their shared notes to its heir, like an
+// account deletion that keeps shared notes. It returns how many notes were transferred.
+func DeleteUser(userID uuid.UUID) (bool, int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, 0, err
+	}
+
+	defer tx.Rollback()
+
+	transferred, err := transferNotesToHeirs(tx, userID)
+	if err != nil {
+		return false, 0, err
+	}
+
+	result, err := tx.Exec("DELETE FROM users WHERE id = $1", userID)
+	if err != nil {
+		return false, 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, 0, err
+	}
+	if rowsAffected == 0 {
+		return false, 0, nil
+	}
+
+	return true, transferred, tx.Commit()
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+const (
+	// apiTokenPrefix makes tokens recognizable, e.g. by secret scanners.
+	apiTokenPrefix = "ntz_"
+	// apiTokenTouchInterval limits how often last_used_at is written.
+	apiTokenTouchInterval = time.Minute
+)
+
+// CreateAPIToken stores a new personal access token and returns it together with the plain
+// token, which is only available at this point.
+func CreateAPIToken(userID uuid.UUID, body *model.APITokenInput) (*model.CreatedAPIToken, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	token, _, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	token = apiTokenPrefix + token
+
+	var expiresAt *time.Time
+	if body.ExpiresInDays != nil {
+		t := time.Now().Add(time.Duration(*body.ExpiresInDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+
+	t := model.CreatedAPIToken{Token: token}
+	var scopes []byte
+	query := `
+		INSERT INTO api_tokens (id, user_id, name, token_hash, token_prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6::TEXT[], $7)
+		RETURNING id, name, token_prefix, array_to_json(scopes), expires_at, last_used_at, created_at
+	`
+	err = db.DB.
+		QueryRow(query, id, userID, body.Name, HashToken(token), token[:len(apiTokenPrefix)+8], body.Scopes, expiresAt).
+		Scan(&t.ID, &t.Name, &t.Prefix, &scopes, &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(scopes, &t.Scopes); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func GetAPITokens(userID uuid.UUID) ([]model.APIToken, error) {
+	query := `
+		SELECT id, name, token_prefix, array_to_json(scopes), expires_at, last_used_at, created_at
+		FROM api_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []model.APIToken{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var t model.APIToken
+		var scopes []byte
+		if err := rows.Scan(
+			&t.ID,
+			&t.Name,
+			&t.Prefix,
+			&scopes,
+			&t.ExpiresAt,
+			&t.LastUsedAt,
+			&t.CreatedAt,
+		); err != nil {
+			log.Println(err)
+		}
+		json.Unmarshal(scopes, &t.Scopes)
+		tokens = append(tokens, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func RevokeAPIToken(id, userID uuid.UUID) (bool, error) {
+	query := "DELETE FROM api_tokens WHERE id = $1 AND user_id = $2"
+	result, err := db.DB.Exec(query, id, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+// GetUserByAPIToken returns the owner of a valid token with the token's ID and scopes set.
+func GetUserByAPIToken(token string) (*model.AuthUser, error) {
+	var u model.AuthUser
+	var tokenID uuid.UUID
+	var scopes []byte
+	var lastUsedAt *time.Time
+	query := `
+		SELECT u.id, u.name, u.email, u.email_verified_at, u.pending_email, u.totp_enabled_at IS NOT NULL, u.role, u.created_at, u.updated_at,
+			t.id, array_to_json(t.scopes), t.last_used_at
+		FROM api_tokens t
+		JOIN users u
+		ON t.user_id = u.id
+		WHERE t.token_hash = $1 AND (t.expires_at IS NULL OR t.expires_at > NOW())
+			AND u.disabled_at IS NULL
+	`
+	if err := db.DB.
+		QueryRow(query, HashToken(token)).
+		Scan(&u.ID, &u.Name, &u.Email, &u.EmailVerifiedAt, &u.PendingEmail, &u.TwoFactorEnabled, &u.Role, &u.CreatedAt, &u.UpdatedAt, &tokenID, &scopes, &lastUsedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(scopes, &u.Scopes); err != nil {
+		return nil, err
+	}
+	u.TokenID = &tokenID
+
+	if lastUsedAt == nil || time.Since(*lastUsedAt) > apiTokenTouchInterval {
+		query = "UPDATE api_tokens SET last_used_at = NOW() WHERE id = $1"
+		if _, err := db.DB.Exec(query, tokenID); err != nil {
+			log.Println("Error updating API token last used:", err)
+		}
+	}
+	return &u, nil
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/google/uuid"
+)
+
+// RecordAudit appends an entry to the audit log. userID is the account the action concerns and
+// actorID who performed it, nil for the system or an anonymous client.
+func RecordAudit(userID, actorID *uuid.UUID, action, ipAddress string, details map[string]interface{}) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_log (id, user_id, actor_id, action, ip_address, details)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+	`
+	_, err = db.DB.Exec(query, id, userID, actorID, action, ipAddress, detailsJSON)
+	return err
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/amiftachulh/notez-api/model"
+)
+
+func splitLines(s *string) []string {
+	if s == nil || *s == "" {
+		return []string{}
+	}
+	return strings.Split(*s, "\n")
+}
+
+// diffLines returns a line-level diff that turns a into b using the Myers algorithm.
+// Common prefix and suffix are trimmed first to keep the trace small for typical edits.
+func diffLines(a, b []string) []model.DiffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]model.DiffLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		result = append(result, model.DiffLine{Op: "equal", Text: line})
+	}
+	result = append(result, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		result = append(result, model.DiffLine{Op: "equal", Text: line})
+	}
+	return result
+}
+
+func myers(a, b []string) []model.DiffLine {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	// trace[d] holds the furthest reaching x for diagonals -d..d before step d.
+	trace := [][]int{}
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	reversed := []model.DiffLine{}
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev[k-1+d] < prev[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[prevK+d]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, model.DiffLine{Op: "equal", Text: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, model.DiffLine{Op: "insert", Text: b[y-1]})
+		} else {
+			reversed = append(reversed, model.DiffLine{Op: "delete", Text: a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, model.DiffLine{Op: "equal", Text: a[x-1]})
+		x--
+		y--
+	}
+
+	result := make([]model.DiffLine, len(reversed))
+	for i, line := range reversed {
+		result[len(reversed)-1-i] = line
+	}
+	return result
+}
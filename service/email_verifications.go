@@ -0,0 +1,101 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+// CreateEmailVerificationToken replaces any token previously issued for the same user and
+// address and returns the plain token to be emailed.
+func CreateEmailVerificationToken(userID uuid.UUID, email string, expiresAt time.Time) (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	token, hash, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+
+	defer tx.Rollback()
+
+	query := "DELETE FROM email_verification_tokens WHERE user_id = $1 AND email = $2"
+	if _, err = tx.Exec(query, userID, email); err != nil {
+		return "", err
+	}
+
+	query = "INSERT INTO email_verification_tokens (id, user_id, email, token_hash, expires_at) VALUES ($1, $2, $3, $4, $5)"
+	if _, err = tx.Exec(query, id, userID, email, hash, expiresAt); err != nil {
+		return "", err
+	}
+
+	return token, tx.Commit()
+}
+
+func GetEmailVerification(token string) (*model.EmailVerification, error) {
+	var v model.EmailVerification
+	query := `
+		SELECT user_id, email FROM email_verification_tokens
+		WHERE token_hash = $1 AND expires_at > NOW()
+	`
+	if err := db.DB.QueryRow(query, HashToken(token)).Scan(&v.UserID, &v.Email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ConfirmEmail consumes the token and marks the address as verified. A confirmed pending address
+// replaces the current one. It returns false when the token no longer matches the user's current
+// or pending address.
+func ConfirmEmail(token string, v *model.EmailVerification) (bool, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	query := "DELETE FROM email_verification_tokens WHERE token_hash = $1"
+	if _, err = tx.Exec(query, HashToken(token)); err != nil {
+		return false, err
+	}
+
+	query = `
+		UPDATE users
+		SET email = $2,
+			email_verified_at = NOW(),
+			pending_email = CASE WHEN pending_email = $2 THEN NULL ELSE pending_email END
+		WHERE id = $1 AND (email = $2 OR pending_email = $2)
+	`
+	result, err := tx.Exec(query, v.UserID, v.Email)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, tx.Commit()
+	}
+
+	// Invitations sent before the address had an account can now be delivered.
+	if err = convertPendingNoteInvitations(tx, v.UserID, v.Email); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
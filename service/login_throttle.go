@@ -0,0 +1,148 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/google/uuid"
+)
+
+// Failed logins are counted per account and per client IP. Once a key reaches its threshold
+// every further failure locks it, doubling the lockout each time up to loginLockoutMax.
+// Counters start over after a day without failures.
+const (
+	loginAccountThreshold = 5
+	loginIPThreshold      = 20
+	loginLockoutBase      = time.Minute
+	loginLockoutMax       = time.Hour
+	loginFailureWindow    = 24 * time.Hour
+)
+
+func LoginAccountKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+func LoginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// GetLoginRetryAfter returns how long the longest active lockout among the keys still lasts,
+// or 0 when none is locked.
+func GetLoginRetryAfter(keys ...string) (time.Duration, error) {
+	var seconds float64
+	query := `
+		SELECT COALESCE(MAX(EXTRACT(EPOCH FROM locked_until - NOW())), 0)
+		FROM login_throttles
+		WHERE key = ANY($1::TEXT[]) AND locked_until > NOW()
+	`
+	if err := db.DB.QueryRow(query, keys).Scan(&seconds); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// RecordLoginFailure counts a failed attempt for the key and returns the lockout it triggered,
+// or 0 when the key is still under its threshold.
+func RecordLoginFailure(key string, threshold int) (time.Duration, error) {
+	var failures int
+	query := `
+		INSERT INTO login_throttles (key, failures, last_failure_at) VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.last_failure_at < NOW() - $2::BIGINT * INTERVAL '1 second' THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failure_at = NOW()
+		RETURNING failures
+	`
+	if err := db.DB.QueryRow(query, key, int64(loginFailureWindow.Seconds())).Scan(&failures); err != nil {
+		return 0, err
+	}
+	if failures < threshold {
+		return 0, nil
+	}
+
+	lockout := loginLockoutMax
+	if shift := failures - threshold; shift < 16 {
+		lockout = min(loginLockoutBase<<shift, loginLockoutMax)
+	}
+	query = "UPDATE login_throttles SET locked_until = NOW() + $1::BIGINT * INTERVAL '1 second' WHERE key = $2"
+	if _, err := db.DB.Exec(query, int64(lockout.Seconds()), key); err != nil {
+		return 0, err
+	}
+	return lockout, nil
+}
+
+func RecordAccountLoginFailure(email string) (time.Duration, error) {
+	return RecordLoginFailure(LoginAccountKey(email), loginAccountThreshold)
+}
+
+func RecordIPLoginFailure(ip string) (time.Duration, error) {
+	return RecordLoginFailure(LoginIPKey(ip), loginIPThreshold)
+}
+
+// ClearLoginFailures resets the failure count and lockout of the key.
+func ClearLoginFailures(key string) error {
+	query := "DELETE FROM login_throttles WHERE key = $1"
+	_, err := db.DB.Exec(query, key)
+	return err
+}
+
+// CreateAccountUnlockToken returns a token that lets the user lift a lockout of their account.
+func CreateAccountUnlockToken(userID uuid.UUID, expiresAt time.Time) (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	token, hash, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	query := "DELETE FROM account_unlock_tokens WHERE user_id = $1 OR expires_at <= NOW()"
+	if _, err = db.DB.Exec(query, userID); err != nil {
+		return "", err
+	}
+
+	query = "INSERT INTO account_unlock_tokens (id, user_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)"
+	if _, err = db.DB.Exec(query, id, userID, hash, expiresAt); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// UnlockAccount consumes the token and clears the lockout of its user's account. It returns the
+// user, or nil when the token is unknown or expired.
+func UnlockAccount(token string) (*uuid.UUID, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	var email string
+	query := `
+		DELETE FROM account_unlock_tokens t
+		USING users u
+		WHERE t.user_id = u.id AND t.token_hash = $1 AND t.expires_at > NOW()
+		RETURNING u.id, u.email
+	`
+	if err = tx.QueryRow(query, HashToken(token)).Scan(&userID, &email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	query = "DELETE FROM login_throttles WHERE key = $1"
+	if _, err = tx.Exec(query, LoginAccountKey(email)); err != nil {
+		return nil, err
+	}
+
+	return &userID, tx.Commit()
+}
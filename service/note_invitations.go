@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"time"
 
 	"github.com/amiftachulh/notez-api/db"
 	"github.com/amiftachulh/notez-api/model"
@@ -24,9 +25,20 @@ func CheckUserInNote(noteID uuid.UUID, email string) (bool, error) {
 	return exists, err
 }
 
+// invitationStatusColumn reports pending invitations past their expiry as expired, since rows are
+// only marked expired once someone tries to respond to them.
+const invitationStatusColumn = `
+	CASE WHEN ni.status = 'pending' AND ni.expires_at <= NOW() THEN 'expired' ELSE ni.status::TEXT END
+`
+
 func CheckInviteExists(noteID uuid.UUID, targetUserID uuid.UUID) (bool, error) {
 	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM note_invitations WHERE note_id = $1 AND user_id = $2)"
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM note_invitations
+			WHERE note_id = $1 AND user_id = $2 AND status = 'pending' AND expires_at > NOW()
+		)
+	`
 	err := db.DB.QueryRow(query, noteID, targetUserID).Scan(&exists)
 	return exists, err
 }
@@ -36,23 +48,26 @@ func CreateNoteInvitation(
 	targetUserID uuid.UUID,
 	inviterID uuid.UUID,
 	role string,
+	expiresAt time.Time,
 ) error {
 	id, err := uuid.NewV7()
 	if err != nil {
 		return err
 	}
-	query := "INSERT INTO note_invitations (id, note_id, user_id, inviter_id, role) VALUES ($1, $2, $3, $4, $5)"
-	_, err = db.DB.Exec(query, id, noteID, targetUserID, inviterID, role)
+	query := "INSERT INTO note_invitations (id, note_id, user_id, inviter_id, role, expires_at) VALUES ($1, $2, $3, $4, $5, $6)"
+	_, err = db.DB.Exec(query, id, noteID, targetUserID, inviterID, role, expiresAt)
 	return err
 }
 
+// GetNoteInvitations lists the invitations the user can still respond to.
 func GetNoteInvitations(userID uuid.UUID) ([]model.NoteInvitationResponse, error) {
 	query := `
-		SELECT ni.id, n.id, n.title, i.id, i.email, i.name, ni.role, ni.created_at
+		SELECT ni.id, n.id, n.title, i.id, i.email, i.name, ni.role, ni.expires_at, ni.created_at
 		FROM note_invitations ni
 		JOIN notes n ON ni.note_id = n.id
 		JOIN users i ON ni.inviter_id = i.id
-		WHERE ni.user_id = $1
+		WHERE ni.user_id = $1 AND ni.status = 'pending' AND ni.expires_at > NOW()
+		ORDER BY ni.created_at DESC
 	`
 	rows, err := db.DB.Query(query, userID)
 	if err != nil {
@@ -72,6 +87,52 @@ func GetNoteInvitations(userID uuid.UUID) ([]model.NoteInvitationResponse, error
 			&ni.Inviter.Email,
 			&ni.Inviter.Name,
 			&ni.Role,
+			&ni.ExpiresAt,
+			&ni.CreatedAt,
+		); err != nil {
+			log.Println(err)
+		}
+		invitations = append(invitations, ni)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// GetSentNoteInvitations lists invitations the user sent or that were sent for notes the user
+// owns, with their whole status history.
+func GetSentNoteInvitations(userID uuid.UUID) ([]model.SentNoteInvitationResponse, error) {
+	query := `
+		SELECT ni.id, n.id, n.title, u.id, u.email, u.name, ni.role, ` + invitationStatusColumn + `,
+			ni.expires_at, ni.responded_at, ni.created_at
+		FROM note_invitations ni
+		JOIN notes n ON ni.note_id = n.id
+		JOIN users u ON ni.user_id = u.id
+		WHERE ni.inviter_id = $1 OR n.user_id = $1
+		ORDER BY ni.created_at DESC
+	`
+	rows, err := db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []model.SentNoteInvitationResponse{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var ni model.SentNoteInvitationResponse
+		if err := rows.Scan(
+			&ni.ID,
+			&ni.Note.ID,
+			&ni.Note.Title,
+			&ni.Invitee.ID,
+			&ni.Invitee.Email,
+			&ni.Invitee.Name,
+			&ni.Role,
+			&ni.Status,
+			&ni.ExpiresAt,
+			&ni.RespondedAt,
 			&ni.CreatedAt,
 		); err != nil {
 			log.Println(err)
@@ -84,13 +145,20 @@ func GetNoteInvitations(userID uuid.UUID) ([]model.NoteInvitationResponse, error
 	return invitations, nil
 }
 
+// GetNoteInvitationByID returns a pending invitation of the user, including expired ones so the
+// caller can tell them apart from unknown ids.
 func GetNoteInvitationByID(
 	invitationID uuid.UUID,
 	userID uuid.UUID,
 ) (*model.NoteInvitation, error) {
 	var ni model.NoteInvitation
-	query := "SELECT id, note_id, role FROM note_invitations WHERE id = $1 AND user_id = $2"
-	if err := db.DB.QueryRow(query, invitationID, userID).Scan(&ni.ID, &ni.NoteID, &ni.Role); err != nil {
+	query := `
+		SELECT id, note_id, user_id, role, expires_at FROM note_invitations
+		WHERE id = $1 AND user_id = $2 AND status = 'pending'
+	`
+	if err := db.DB.
+		QueryRow(query, invitationID, userID).
+		Scan(&ni.ID, &ni.NoteID, &ni.UserID, &ni.Role, &ni.ExpiresAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -99,13 +167,250 @@ func GetNoteInvitationByID(
 	return &ni, nil
 }
 
-func DeclineInvitation(invitationID uuid.UUID, userID uuid.UUID) error {
-	query := "DELETE FROM note_invitations WHERE id = $1 AND user_id = $2"
-	_, err := db.DB.Exec(query, invitationID, userID)
+func ExpireInvitation(invitationID uuid.UUID) error {
+	query := "UPDATE note_invitations SET status = 'expired' WHERE id = $1 AND status = 'pending'"
+	_, err := db.DB.Exec(query, invitationID)
 	return err
 }
 
-func AcceptInvitation(noteID uuid.UUID, userID uuid.UUID, role string) error {
+func DeclineInvitation(invitationID uuid.UUID, userID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE note_invitations SET status = 'declined', responded_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = 'pending' AND expires_at > NOW()
+	`
+	result, err := db.DB.Exec(query, invitationID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// RevokeInvitation cancels a pending invitation. Only its inviter or the note owner may do so.
+func RevokeInvitation(invitationID uuid.UUID, userID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE note_invitations ni SET status = 'revoked', responded_at = NOW()
+		FROM notes n
+		WHERE ni.note_id = n.id AND ni.id = $1 AND (ni.inviter_id = $2 OR n.user_id = $2)
+			AND ni.status = 'pending'
+	`
+	result, err := db.DB.Exec(query, invitationID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func AcceptInvitation(invitationID uuid.UUID, noteID uuid.UUID, userID uuid.UUID, role string) (bool, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		UPDATE note_invitations SET status = 'accepted', responded_at = NOW()
+		WHERE id = $1 AND status = 'pending' AND expires_at > NOW()
+	`
+	result, err := tx.Exec(query, invitationID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	query = "INSERT INTO notes_users (note_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING"
+	if _, err = tx.Exec(query, noteID, userID, role); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func DeletePendingNoteInvitation(invitationID uuid.UUID, userID uuid.UUID) (bool, error) {
+	query := `
+		DELETE FROM pending_note_invitations pi
+		USING notes n
+		WHERE pi.note_id = n.id AND pi.id = $1 AND (pi.inviter_id = $2 OR n.user_id = $2)
+	`
+	result, err := db.DB.Exec(query, invitationID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// Invitation emails go through the login throttle so the mailer can't be used to spam. Each
+// inviter can send invitationEmailThreshold emails a day before further ones back off like failed
+// logins, and resending the same invitation backs off from the first resend.
+const (
+	invitationEmailThreshold  = 20
+	invitationResendThreshold = 1
+)
+
+func InvitationEmailKey(inviterID uuid.UUID) string {
+	return "invitation-email:" + inviterID.String()
+}
+
+func InvitationResendKey(invitationID uuid.UUID) string {
+	return "invitation-resend:" + invitationID.String()
+}
+
+func RecordInvitationEmail(inviterID uuid.UUID) error {
+	_, err := RecordLoginFailure(InvitationEmailKey(inviterID), invitationEmailThreshold)
+	return err
+}
+
+func RecordInvitationResend(inviterID uuid.UUID, invitationID uuid.UUID) error {
+	if err := RecordInvitationEmail(inviterID); err != nil {
+		return err
+	}
+	_, err := RecordLoginFailure(InvitationResendKey(invitationID), invitationResendThreshold)
+	return err
+}
+
+func CheckPendingInviteExists(noteID uuid.UUID, email string) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM pending_note_invitations WHERE note_id = $1 AND email = $2)"
+	err := db.DB.QueryRow(query, noteID, email).Scan(&exists)
+	return exists, err
+}
+
+// CreatePendingNoteInvitation invites an address that has no account yet. It returns the plain
+// acceptance token to be emailed and the note title for the message.
+func CreatePendingNoteInvitation(
+	noteID uuid.UUID,
+	email string,
+	inviterID uuid.UUID,
+	role string,
+	expiresAt time.Time,
+) (string, string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", "", err
+	}
+	token, hash, err := GenerateToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	var title string
+	query := `
+		INSERT INTO pending_note_invitations (id, note_id, email, inviter_id, role, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING (SELECT title FROM notes WHERE id = $2)
+	`
+	err = db.DB.
+		QueryRow(query, id, noteID, email, inviterID, role, hash, expiresAt).
+		Scan(&title)
+	return token, title, err
+}
+
+func GetPendingNoteInvitations(inviterID uuid.UUID) ([]model.PendingNoteInvitationResponse, error) {
+	query := `
+		SELECT pi.id, n.id, n.title, pi.email, pi.role, pi.expires_at, pi.created_at
+		FROM pending_note_invitations pi
+		JOIN notes n ON pi.note_id = n.id
+		WHERE pi.inviter_id = $1
+		ORDER BY pi.created_at DESC
+	`
+	rows, err := db.DB.Query(query, inviterID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []model.PendingNoteInvitationResponse{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var pi model.PendingNoteInvitationResponse
+		if err := rows.Scan(
+			&pi.ID,
+			&pi.Note.ID,
+			&pi.Note.Title,
+			&pi.Email,
+			&pi.Role,
+			&pi.ExpiresAt,
+			&pi.CreatedAt,
+		); err != nil {
+			log.Println(err)
+		}
+		invitations = append(invitations, pi)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// RenewPendingNoteInvitation replaces the token of an invitation sent by the user and pushes its
+// expiry back. It returns nil when no such invitation exists.
+func RenewPendingNoteInvitation(
+	invitationID uuid.UUID,
+	inviterID uuid.UUID,
+	expiresAt time.Time,
+) (*model.PendingNoteInvitation, string, string, error) {
+	token, hash, err := GenerateToken()
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var pi model.PendingNoteInvitation
+	var title string
+	query := `
+		UPDATE pending_note_invitations SET token_hash = $3, expires_at = $4
+		WHERE id = $1 AND inviter_id = $2
+		RETURNING id, note_id, email, role, expires_at, (SELECT title FROM notes WHERE id = note_id)
+	`
+	if err = db.DB.
+		QueryRow(query, invitationID, inviterID, hash, expiresAt).
+		Scan(&pi.ID, &pi.NoteID, &pi.Email, &pi.Role, &pi.ExpiresAt, &title); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", "", nil
+		}
+		return nil, "", "", err
+	}
+	return &pi, token, title, nil
+}
+
+func GetPendingNoteInvitationByToken(token string) (*model.PendingNoteInvitation, error) {
+	var pi model.PendingNoteInvitation
+	query := `
+		SELECT id, note_id, email, role, expires_at FROM pending_note_invitations
+		WHERE token_hash = $1 AND expires_at > NOW()
+	`
+	if err := db.DB.
+		QueryRow(query, HashToken(token)).
+		Scan(&pi.ID, &pi.NoteID, &pi.Email, &pi.Role, &pi.ExpiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pi, nil
+}
+
+// AcceptPendingNoteInvitation is used right after an invited address registers. Following the
+// emailed link proves the address, so it is marked verified, the invitation becomes a membership
+// and any other invitations for the address become regular ones.
+func AcceptPendingNoteInvitation(userID uuid.UUID, email string, invitationID uuid.UUID) error {
 	tx, err := db.DB.Begin()
 	if err != nil {
 		return err
@@ -113,15 +418,85 @@ func AcceptInvitation(noteID uuid.UUID, userID uuid.UUID, role string) error {
 
 	defer tx.Rollback()
 
-	query := "DELETE FROM note_invitations WHERE note_id = $1 AND user_id = $2"
-	if _, err = tx.Exec(query, noteID, userID); err != nil {
+	query := "UPDATE users SET email_verified_at = NOW() WHERE id = $1 AND email = $2"
+	if _, err = tx.Exec(query, userID, email); err != nil {
 		return err
 	}
 
-	query = "INSERT INTO notes_users (note_id, user_id, role) VALUES ($1, $2, $3)"
-	if _, err = tx.Exec(query, noteID, userID, role); err != nil {
+	query = `
+		WITH accepted AS (
+			DELETE FROM pending_note_invitations
+			WHERE id = $2 AND email = $3 AND expires_at > NOW()
+			RETURNING note_id, role
+		)
+		INSERT INTO notes_users (note_id, user_id, role)
+		SELECT note_id, $1::UUID, role FROM accepted
+		ON CONFLICT DO NOTHING
+	`
+	if _, err = tx.Exec(query, userID, invitationID, email); err != nil {
+		return err
+	}
+
+	if err = convertPendingNoteInvitations(tx, userID, email); err != nil {
 		return err
 	}
 
 	return tx.Commit()
 }
+
+// convertPendingNoteInvitations turns the unexpired invitations for the address into regular
+// invitations of the user, who then accepts or declines them like any other. They keep their
+// expiry. Expired ones are dropped.
+func convertPendingNoteInvitations(tx *sql.Tx, userID uuid.UUID, email string) error {
+	query := `
+		DELETE FROM pending_note_invitations
+		WHERE email = $1
+		RETURNING note_id, inviter_id, role, expires_at
+	`
+	rows, err := tx.Query(query, email)
+	if err != nil {
+		return err
+	}
+
+	var invitations []model.NoteInvitation
+	var inviterIDs []uuid.UUID
+
+	defer rows.Close()
+	for rows.Next() {
+		var ni model.NoteInvitation
+		var inviterID uuid.UUID
+		if err := rows.Scan(&ni.NoteID, &inviterID, &ni.Role, &ni.ExpiresAt); err != nil {
+			return err
+		}
+		if ni.ExpiresAt.After(time.Now()) {
+			invitations = append(invitations, ni)
+			inviterIDs = append(inviterIDs, inviterID)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	query = `
+		INSERT INTO note_invitations (id, note_id, user_id, inviter_id, role, expires_at)
+		SELECT $1::UUID, $2::UUID, $3::UUID, $4::UUID, $5::note_role, $6::TIMESTAMPTZ
+		WHERE NOT EXISTS(
+				SELECT 1 FROM note_invitations
+				WHERE note_id = $2 AND user_id = $3 AND status = 'pending' AND expires_at > NOW()
+			)
+			AND NOT EXISTS(SELECT 1 FROM notes_users WHERE note_id = $2 AND user_id = $3)
+			AND NOT EXISTS(SELECT 1 FROM notes WHERE id = $2 AND user_id = $3)
+	`
+	for i, ni := range invitations {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(query, id, ni.NoteID, userID, inviterIDs[i], ni.Role, ni.ExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+const noteJoinLinkColumns = `
+	id, note_id, role, max_uses, use_count, allowed_domain, expires_at, revoked_at, created_at
+`
+
+func scanNoteJoinLink(scanner interface{ Scan(...interface{}) error }, l *model.NoteJoinLink) error {
+	return scanner.Scan(
+		&l.ID,
+		&l.NoteID,
+		&l.Role,
+		&l.MaxUses,
+		&l.UseCount,
+		&l.AllowedDomain,
+		&l.ExpiresAt,
+		&l.RevokedAt,
+		&l.CreatedAt,
+	)
+}
+
+// CreateNoteJoinLink stores a new join link and returns it together with the plain token, which
+// is only available at this point.
+func CreateNoteJoinLink(
+	noteID uuid.UUID,
+	userID uuid.UUID,
+	body *model.NoteJoinLinkInput,
+) (*model.CreatedNoteJoinLink, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	token, hash, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if body.ExpiresInDays != nil {
+		t := time.Now().Add(time.Duration(*body.ExpiresInDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+
+	var allowedDomain *string
+	if body.AllowedDomain != nil {
+		d := strings.ToLower(*body.AllowedDomain)
+		allowedDomain = &d
+	}
+
+	l := model.CreatedNoteJoinLink{Token: token}
+	query := `
+		INSERT INTO note_join_links (id, note_id, created_by, token_hash, role, max_uses, allowed_domain, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + noteJoinLinkColumns
+	err = scanNoteJoinLink(
+		db.DB.QueryRow(query, id, noteID, userID, hash, body.Role, body.MaxUses, allowedDomain, expiresAt),
+		&l.NoteJoinLink,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func GetNoteJoinLinks(noteID uuid.UUID) ([]model.NoteJoinLink, error) {
+	query := "SELECT " + noteJoinLinkColumns + " FROM note_join_links WHERE note_id = $1 ORDER BY created_at DESC"
+	rows, err := db.DB.Query(query, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	links := []model.NoteJoinLink{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var l model.NoteJoinLink
+		if err := scanNoteJoinLink(rows, &l); err != nil {
+			log.Println(err)
+		}
+		links = append(links, l)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func RevokeNoteJoinLink(linkID, noteID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE note_join_links SET revoked_at = NOW()
+		WHERE id = $1 AND note_id = $2 AND revoked_at IS NULL
+	`
+	result, err := db.DB.Exec(query, linkID, noteID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// GetNoteJoinLinkByToken returns the link whatever its state, so callers can explain why it
+// can't be used.
+func GetNoteJoinLinkByToken(token string) (*model.NoteJoinLink, error) {
+	var l model.NoteJoinLink
+	query := "SELECT " + noteJoinLinkColumns + " FROM note_join_links WHERE token_hash = $1"
+	if err := scanNoteJoinLink(db.DB.QueryRow(query, HashToken(token)), &l); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// JoinNoteByLink adds the user to the note with the link's role and uses up one use of the link
+// when the user wasn't a member yet. Any pending invitation of the user to the note is settled as
+// accepted. It returns false when the link is no longer usable or the note is in the trash.
+func JoinNoteByLink(link *model.NoteJoinLink, userID uuid.UUID) (bool, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	// Locking the link keeps concurrent joins from going over max_uses.
+	var usable bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM notes n
+			WHERE n.id = l.note_id AND n.deleted_at IS NULL
+		)
+		FROM note_join_links l
+		WHERE l.id = $1 AND l.revoked_at IS NULL
+			AND (l.expires_at IS NULL OR l.expires_at > NOW())
+			AND (l.max_uses IS NULL OR l.use_count < l.max_uses)
+		FOR UPDATE
+	`
+	if err = tx.QueryRow(query, link.ID).Scan(&usable); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !usable {
+		return false, nil
+	}
+
+	query = "INSERT INTO notes_users (note_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING"
+	result, err := tx.Exec(query, link.NoteID, userID, link.Role)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if rowsAffected > 0 {
+		query = "UPDATE note_join_links SET use_count = use_count + 1 WHERE id = $1"
+		if _, err = tx.Exec(query, link.ID); err != nil {
+			return false, err
+		}
+	}
+
+	query = `
+		UPDATE note_invitations SET status = 'accepted', responded_at = NOW()
+		WHERE note_id = $1 AND user_id = $2 AND status = 'pending'
+	`
+	if _, err = tx.Exec(query, link.NoteID, userID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
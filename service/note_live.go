@@ -0,0 +1,431 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/ot"
+	"github.com/google/uuid"
+)
+
+const (
+	livePersistInterval = 5 * time.Second
+	// liveAccessInterval is how often connected clients' roles are reloaded, so removed members
+	// are disconnected and demoted editors stop editing.
+	liveAccessInterval = 10 * time.Second
+	liveHistoryLimit   = 1000
+	liveSendBuffer     = 64
+)
+
+// LiveClient is a connection to a live note. Role is guarded by the document's lock once the
+// client has joined.
+type LiveClient struct {
+	UserID uuid.UUID
+	Role   string
+	Send   chan model.LiveMessage
+}
+
+func NewLiveClient(userID uuid.UUID, role string) *LiveClient {
+	return &LiveClient{
+		UserID: userID,
+		Role:   role,
+		Send:   make(chan model.LiveMessage, liveSendBuffer),
+	}
+}
+
+// liveDocument is the in-memory copy of a note being edited live. While at least one client is
+// connected it is the source of truth for the note content and is saved periodically.
+//
+// version is the stored note version the document last loaded or saved, saved the content it
+// had then and savedRevision the revision at which the document matched it, or -1 if none did.
+// They let a save made elsewhere in the meantime be merged instead of overwritten.
+//
+// closed is set once the document is removed from liveDocuments, so a client that found it just
+// before then opens the note again.
+type liveDocument struct {
+	mu            sync.Mutex
+	noteID        uuid.UUID
+	content       string
+	revision      int
+	base          int
+	history       []*ot.Operation
+	clients       map[*LiveClient]struct{}
+	dirty         bool
+	lastEditor    uuid.UUID
+	timer         *time.Timer
+	version       int
+	saved         string
+	savedRevision int
+	closed        bool
+}
+
+var (
+	liveMu        sync.Mutex
+	liveDocuments = map[uuid.UUID]*liveDocument{}
+)
+
+// JoinLiveNote registers the client on the note and queues the "init" message with the current
+// content and revision. A note that isn't open yet is loaded without liveMu, and a document opened
+// by another client meanwhile is used instead.
+func JoinLiveNote(noteID uuid.UUID, client *LiveClient) error {
+	for {
+		doc, err := openLiveDocument(noteID)
+		if err != nil {
+			return err
+		}
+
+		doc.mu.Lock()
+		if !doc.closed {
+			doc.join(client)
+			doc.mu.Unlock()
+			return nil
+		}
+		doc.mu.Unlock()
+	}
+}
+
+// openLiveDocument returns the open document for the note, loading it if there is none.
+func openLiveDocument(noteID uuid.UUID) (*liveDocument, error) {
+	liveMu.Lock()
+	doc, ok := liveDocuments[noteID]
+	liveMu.Unlock()
+	if ok {
+		return doc, nil
+	}
+
+	var content *string
+	var version int
+	query := "SELECT content, version FROM notes WHERE id = $1"
+	if err := db.DB.QueryRow(query, noteID).Scan(&content, &version); err != nil {
+		return nil, err
+	}
+
+	liveMu.Lock()
+	defer liveMu.Unlock()
+
+	if doc, ok := liveDocuments[noteID]; ok {
+		return doc, nil
+	}
+	doc = &liveDocument{
+		noteID:  noteID,
+		clients: map[*LiveClient]struct{}{},
+		version: version,
+	}
+	if content != nil {
+		doc.content = *content
+	}
+	doc.saved = doc.content
+	liveDocuments[noteID] = doc
+	return doc, nil
+}
+
+// join adds the client, sends it the current state and announces it to the others. The caller
+// must hold doc.mu.
+func (doc *liveDocument) join(client *LiveClient) {
+	users := make([]uuid.UUID, 0, len(doc.clients)+1)
+	for c := range doc.clients {
+		users = append(users, c.UserID)
+	}
+	users = append(users, client.UserID)
+
+	doc.clients[client] = struct{}{}
+	content := doc.content
+	client.Send <- model.LiveMessage{
+		Type:     "init",
+		Revision: doc.revision,
+		Content:  &content,
+		Role:     client.Role,
+		Users:    users,
+	}
+	doc.broadcast(client, model.LiveMessage{
+		Type:     "join",
+		Revision: doc.revision,
+		UserID:   &client.UserID,
+	})
+}
+
+// LeaveLiveNote unregisters the client. The last client to leave saves pending changes and
+// releases the document. The save runs without liveMu so other notes aren't held up by the
+// database; a client joining meanwhile waits for it and keeps the document open.
+func LeaveLiveNote(noteID uuid.UUID, client *LiveClient) {
+	liveMu.Lock()
+	doc, ok := liveDocuments[noteID]
+	liveMu.Unlock()
+	if !ok {
+		return
+	}
+
+	doc.mu.Lock()
+	doc.remove(client)
+	last := len(doc.clients) == 0
+	if last {
+		if doc.timer != nil {
+			doc.timer.Stop()
+		}
+		doc.persist()
+	}
+	doc.mu.Unlock()
+	if !last {
+		return
+	}
+
+	liveMu.Lock()
+	defer liveMu.Unlock()
+
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	if len(doc.clients) == 0 && liveDocuments[noteID] == doc {
+		delete(liveDocuments, noteID)
+		doc.closed = true
+	}
+}
+
+// SubmitLiveOperation transforms the operation against everything applied since the client's
+// revision, applies it and broadcasts it to the other clients.
+func SubmitLiveOperation(noteID uuid.UUID, client *LiveClient, revision int, op *ot.Operation) {
+	liveMu.Lock()
+	doc, ok := liveDocuments[noteID]
+	liveMu.Unlock()
+	if !ok {
+		return
+	}
+
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	if _, ok := doc.clients[client]; !ok {
+		return
+	}
+	if client.Role == "viewer" {
+		doc.send(client, liveError("Viewers can't edit this note."))
+		return
+	}
+	if revision < doc.base || revision > doc.revision {
+		doc.send(client, liveError("Revision is out of range. Reconnect to resync."))
+		return
+	}
+
+	var err error
+	for _, concurrent := range doc.history[revision-doc.base:] {
+		if op, _, err = ot.Transform(op, concurrent); err != nil {
+			doc.send(client, liveError("Operation can't be applied to this revision."))
+			return
+		}
+	}
+
+	content, err := op.Apply(doc.content)
+	if err != nil {
+		doc.send(client, liveError("Operation can't be applied to this revision."))
+		return
+	}
+	if len(content) > model.NOTE_MAX_CONTENT_BYTES {
+		doc.send(client, liveError("Content size must be less than 5 MB."))
+		return
+	}
+
+	doc.apply(op, content)
+	doc.lastEditor = client.UserID
+	if !doc.dirty {
+		doc.dirty = true
+		doc.timer = time.AfterFunc(livePersistInterval, doc.persistLocked)
+	}
+
+	doc.send(client, model.LiveMessage{Type: "ack", Revision: doc.revision})
+	doc.broadcast(client, model.LiveMessage{
+		Type:     "op",
+		Revision: doc.revision,
+		Ops:      op,
+		UserID:   &client.UserID,
+	})
+}
+
+// WatchLiveAccess reloads the client's role every liveAccessInterval until done is closed.
+func WatchLiveAccess(noteID uuid.UUID, client *LiveClient, done <-chan struct{}) {
+	ticker := time.NewTicker(liveAccessInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := refreshLiveRole(noteID, client); err != nil {
+				log.Println("Error refreshing live note role:", err)
+			}
+		}
+	}
+}
+
+// refreshLiveRole applies the client's current role to the connection. A client that lost
+// access is disconnected, one whose role changed is told with a "role" message.
+func refreshLiveRole(noteID uuid.UUID, client *LiveClient) error {
+	role, err := GetNoteRole(noteID, client.UserID)
+	if err != nil {
+		return err
+	}
+
+	liveMu.Lock()
+	doc, ok := liveDocuments[noteID]
+	liveMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	if _, ok := doc.clients[client]; !ok {
+		return nil
+	}
+	if role == nil {
+		doc.send(client, liveError("You no longer have access to this note."))
+		doc.remove(client)
+		return nil
+	}
+	if *role != client.Role {
+		client.Role = *role
+		doc.send(client, model.LiveMessage{Type: "role", Revision: doc.revision, Role: *role})
+	}
+	return nil
+}
+
+// SendLiveError queues an error message for the client if it is still connected.
+func SendLiveError(noteID uuid.UUID, client *LiveClient, message string) {
+	liveMu.Lock()
+	doc, ok := liveDocuments[noteID]
+	liveMu.Unlock()
+	if !ok {
+		return
+	}
+
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	doc.send(client, liveError(message))
+}
+
+func liveError(message string) model.LiveMessage {
+	return model.LiveMessage{Type: "error", Message: message}
+}
+
+// send queues a message without blocking. A client that can't keep up is disconnected since
+// skipping operations would leave its copy diverged. The caller must hold doc.mu.
+func (doc *liveDocument) send(client *LiveClient, msg model.LiveMessage) {
+	if _, ok := doc.clients[client]; !ok {
+		return
+	}
+	select {
+	case client.Send <- msg:
+	default:
+		doc.remove(client)
+	}
+}
+
+func (doc *liveDocument) broadcast(from *LiveClient, msg model.LiveMessage) {
+	for c := range doc.clients {
+		if c != from {
+			doc.send(c, msg)
+		}
+	}
+}
+
+func (doc *liveDocument) remove(client *LiveClient) {
+	if _, ok := doc.clients[client]; !ok {
+		return
+	}
+	delete(doc.clients, client)
+	close(client.Send)
+	doc.broadcast(nil, model.LiveMessage{
+		Type:     "leave",
+		Revision: doc.revision,
+		UserID:   &client.UserID,
+	})
+}
+
+// apply records an operation that turned the document into content as the next revision. The
+// caller must hold doc.mu.
+func (doc *liveDocument) apply(op *ot.Operation, content string) {
+	doc.content = content
+	doc.revision++
+	doc.history = append(doc.history, op)
+	if len(doc.history) > liveHistoryLimit {
+		doc.base += len(doc.history) - liveHistoryLimit
+		doc.history = doc.history[len(doc.history)-liveHistoryLimit:]
+	}
+}
+
+func (doc *liveDocument) persistLocked() {
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	doc.persist()
+}
+
+// persist saves the content through the notes service when it has changed. The caller must
+// hold doc.mu.
+func (doc *liveDocument) persist() {
+	if !doc.dirty {
+		return
+	}
+
+	version, err := updateNoteContent(doc.noteID, doc.lastEditor, doc.merge)
+	if err != nil {
+		log.Println("Error persisting live note:", err)
+		if len(doc.clients) > 0 {
+			doc.timer = time.AfterFunc(livePersistInterval, doc.persistLocked)
+		}
+		return
+	}
+	if version != nil {
+		doc.version = *version
+		doc.saved = doc.content
+		doc.savedRevision = doc.revision
+	}
+	doc.dirty = false
+}
+
+// merge returns the content to save over the stored note. When the note was saved elsewhere
+// since the document last loaded or saved it, that change is transformed over the live edits
+// made since and applied like an operation from another client. The caller must hold doc.mu.
+func (doc *liveDocument) merge(stored *string, version int) (*string, error) {
+	if version != doc.version {
+		current := ""
+		if stored != nil {
+			current = *stored
+		}
+
+		var op *ot.Operation
+		var err error
+		if doc.savedRevision < doc.base {
+			// The history no longer reaches back to the last save, so the stored content wins.
+			op = ot.Diff(doc.content, current)
+		} else {
+			op = ot.Diff(doc.saved, current)
+			for _, concurrent := range doc.history[doc.savedRevision-doc.base:] {
+				if op, _, err = ot.Transform(op, concurrent); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		content, err := op.Apply(doc.content)
+		if err != nil {
+			return nil, err
+		}
+		if !op.IsNoop() {
+			doc.apply(op, content)
+			doc.broadcast(nil, model.LiveMessage{Type: "op", Revision: doc.revision, Ops: op})
+		}
+		doc.version = version
+		doc.saved = current
+		doc.savedRevision = -1
+	}
+
+	if doc.content == "" {
+		return nil, nil
+	}
+	content := doc.content
+	return &content, nil
+}
@@ -7,7 +7,7 @@ import (
 
 func CheckIsNoteOwner(noteID, userID uuid.UUID) (bool, error) {
 	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM notes WHERE id = $1 AND user_id = $2)"
+	query := "SELECT EXISTS(SELECT 1 FROM notes WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)"
 	err := db.DB.QueryRow(query, noteID, userID).Scan(&exists)
 	return exists, err
 }
@@ -30,9 +30,28 @@ func UpdateNoteMemberRole(noteID, memberID uuid.UUID, role string) (bool, error)
 	return true, nil
 }
 
+// RemoveNoteMember removes the member and revokes any pending transfer of the note to them, so a
+// transfer can't be used to take over a note after losing access to it. The transfer is revoked
+// first so a concurrent AcceptNoteTransfer either finishes before the removal or finds nothing to
+// accept.
 func RemoveNoteMember(noteID, memberID uuid.UUID) (bool, error) {
-	query := "DELETE FROM notes_users WHERE note_id = $1 AND user_id = $2"
-	result, err := db.DB.Exec(query, noteID, memberID)
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		UPDATE note_transfers SET status = 'revoked', responded_at = NOW()
+		WHERE note_id = $1 AND to_user_id = $2 AND status = 'pending'
+	`
+	if _, err = tx.Exec(query, noteID, memberID); err != nil {
+		return false, err
+	}
+
+	query = "DELETE FROM notes_users WHERE note_id = $1 AND user_id = $2"
+	result, err := tx.Exec(query, noteID, memberID)
 	if err != nil {
 		return false, err
 	}
@@ -45,5 +64,5 @@ func RemoveNoteMember(noteID, memberID uuid.UUID) (bool, error) {
 		return false, nil
 	}
 
-	return true, nil
+	return true, tx.Commit()
 }
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+// Wrong passwords for a published note are counted per client, which is locked out after
+// publicationPasswordThreshold failures with the same backoff as logins. A much higher count for
+// the note as a whole only backs that up against guessing spread over many addresses.
+const (
+	publicationPasswordThreshold     = 10
+	publicationPasswordSlugThreshold = 500
+)
+
+// PublicationKeys returns the throttle keys for password attempts on the note from the IP.
+func PublicationKeys(slug, ip string) []string {
+	return []string{"publication:" + slug + ":" + LoginIPKey(ip), "publication:" + slug}
+}
+
+func RecordPublicationPasswordFailure(slug, ip string) (time.Duration, error) {
+	keys := PublicationKeys(slug, ip)
+	retryAfter, err := RecordLoginFailure(keys[0], publicationPasswordThreshold)
+	if err != nil {
+		return 0, err
+	}
+	slugRetryAfter, err := RecordLoginFailure(keys[1], publicationPasswordSlugThreshold)
+	if err != nil {
+		return 0, err
+	}
+	return max(retryAfter, slugRetryAfter), nil
+}
+
+// PublishNote publishes the note or updates its settings. The slug is generated on the first
+// publish and kept until the note is unpublished.
+func PublishNote(
+	noteID uuid.UUID,
+	passwordHash *string,
+	expiresAt *time.Time,
+) (*model.NotePublication, error) {
+	slug, _, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var p model.NotePublication
+	query := `
+		INSERT INTO note_publications (note_id, slug, password, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (note_id) DO UPDATE SET password = EXCLUDED.password, expires_at = EXCLUDED.expires_at
+		RETURNING slug, password IS NOT NULL, expires_at, created_at, updated_at
+	`
+	err = db.DB.
+		QueryRow(query, noteID, slug, passwordHash, expiresAt).
+		Scan(&p.Slug, &p.PasswordProtected, &p.ExpiresAt, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func GetNotePublication(noteID uuid.UUID) (*model.NotePublication, error) {
+	var p model.NotePublication
+	query := `
+		SELECT slug, password IS NOT NULL, expires_at, created_at, updated_at
+		FROM note_publications
+		WHERE note_id = $1
+	`
+	if err := db.DB.
+		QueryRow(query, noteID).
+		Scan(&p.Slug, &p.PasswordProtected, &p.ExpiresAt, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func UnpublishNote(noteID uuid.UUID) (bool, error) {
+	result, err := db.DB.Exec("DELETE FROM note_publications WHERE note_id = $1", noteID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// GetPublishedNote returns the note behind the slug unless the publication expired or the note
+// is in the trash. Password holds the hash to check when the note is protected.
+func GetPublishedNote(slug string) (*model.PublicNote, error) {
+	var n model.PublicNote
+	query := `
+		SELECT n.title, n.content, u.name, p.created_at, n.updated_at, p.expires_at, p.password
+		FROM note_publications p
+		JOIN notes n ON p.note_id = n.id
+		JOIN users u ON n.user_id = u.id
+		WHERE p.slug = $1 AND (p.expires_at IS NULL OR p.expires_at > NOW()) AND n.deleted_at IS NULL
+	`
+	if err := db.DB.
+		QueryRow(query, slug).
+		Scan(&n.Title, &n.Content, &n.Author, &n.PublishedAt, &n.UpdatedAt, &n.ExpiresAt, &n.Password); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &n, nil
+}
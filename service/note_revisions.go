@@ -0,0 +1,135 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+func createNoteRevision(
+	tx *sql.Tx,
+	noteID uuid.UUID,
+	userID uuid.UUID,
+	title string,
+	content *string,
+) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	query := "INSERT INTO note_revisions (id, note_id, user_id, title, content) VALUES ($1, $2, $3, $4, $5)"
+	_, err = tx.Exec(query, id, noteID, userID, title, content)
+	return err
+}
+
+func GetNoteRevisions(noteID uuid.UUID) ([]model.NoteRevision, error) {
+	query := `
+		SELECT r.id, r.note_id, r.title, u.id, u.email, u.name, r.created_at
+		FROM note_revisions r
+		LEFT JOIN users u ON r.user_id = u.id
+		WHERE r.note_id = $1
+		ORDER BY r.created_at DESC, r.id DESC
+	`
+	rows, err := db.DB.Query(query, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []model.NoteRevision{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var r model.NoteRevision
+		var authorID uuid.NullUUID
+		var authorEmail sql.NullString
+		var authorName *string
+		if err := rows.Scan(
+			&r.ID,
+			&r.NoteID,
+			&r.Title,
+			&authorID,
+			&authorEmail,
+			&authorName,
+			&r.CreatedAt,
+		); err != nil {
+			log.Println(err)
+		}
+		if authorID.Valid {
+			r.Author = &model.User{ID: authorID.UUID, Email: authorEmail.String, Name: authorName}
+		}
+		revisions = append(revisions, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetNoteRevisionByID returns the revision along with a line diff from the revision content
+// to the current note content.
+func GetNoteRevisionByID(noteID, revisionID uuid.UUID) (*model.NoteRevisionDetail, error) {
+	var r model.NoteRevisionDetail
+	var authorID uuid.NullUUID
+	var authorEmail sql.NullString
+	var authorName *string
+	var currentContent *string
+	query := `
+		SELECT r.id, r.note_id, r.title, r.content, u.id, u.email, u.name, r.created_at, n.title, n.content
+		FROM note_revisions r
+		JOIN notes n ON r.note_id = n.id
+		LEFT JOIN users u ON r.user_id = u.id
+		WHERE r.id = $1 AND r.note_id = $2
+	`
+	err := db.DB.
+		QueryRow(query, revisionID, noteID).
+		Scan(&r.ID, &r.NoteID, &r.Title, &r.Content, &authorID, &authorEmail, &authorName, &r.CreatedAt, &r.CurrentTitle, &currentContent)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if authorID.Valid {
+		r.Author = &model.User{ID: authorID.UUID, Email: authorEmail.String, Name: authorName}
+	}
+
+	r.Diff = diffLines(splitLines(r.Content), splitLines(currentContent))
+	return &r, nil
+}
+
+// RestoreNoteRevision copies the revision title and content back into the note and records
+// the restore as a new revision authored by userID.
+func RestoreNoteRevision(noteID, revisionID, userID uuid.UUID) (bool, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	var title string
+	var content *string
+	query := `
+		UPDATE notes n
+		SET title = r.title, content = r.content, version = n.version + 1
+		FROM note_revisions r
+		WHERE n.id = $1 AND r.id = $2 AND r.note_id = n.id AND n.deleted_at IS NULL
+		RETURNING n.title, n.content
+	`
+	if err = tx.QueryRow(query, noteID, revisionID).Scan(&title, &content); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err = createNoteRevision(tx, noteID, userID, title, content); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
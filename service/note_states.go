@@ -0,0 +1,33 @@
+package service
+
+import (
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+// UpdateNoteState sets the user's own pinned, archived and favorite flags on a note. Flags left
+// nil in the body keep their current value.
+func UpdateNoteState(
+	noteID uuid.UUID,
+	userID uuid.UUID,
+	body *model.UpdateNoteState,
+) (*model.NoteState, error) {
+	var s model.NoteState
+	query := `
+		INSERT INTO note_states (note_id, user_id, pinned, archived, favorite)
+		VALUES ($1, $2, COALESCE($3, FALSE), COALESCE($4, FALSE), COALESCE($5, FALSE))
+		ON CONFLICT (note_id, user_id) DO UPDATE SET
+			pinned = COALESCE($3, note_states.pinned),
+			archived = COALESCE($4, note_states.archived),
+			favorite = COALESCE($5, note_states.favorite)
+		RETURNING pinned, archived, favorite
+	`
+	err := db.DB.
+		QueryRow(query, noteID, userID, body.Pinned, body.Archived, body.Favorite).
+		Scan(&s.Pinned, &s.Archived, &s.Favorite)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
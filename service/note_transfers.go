@@ -0,0 +1,321 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+// noteHeirsQuery picks, for every live note owned by $1 with direct members, the member who has
+// been an editor the longest, falling back to the oldest viewer.
+const noteHeirsQuery = `
+	SELECT DISTINCT ON (nu.note_id) nu.note_id, nu.user_id
+	FROM notes_users nu
+	JOIN notes n ON nu.note_id = n.id
+	WHERE n.user_id = $1 AND n.deleted_at IS NULL
+	ORDER BY nu.note_id, nu.role = 'editor' DESC, nu.created_at
+`
+
+const noteTransferStatusColumn = `
+	CASE WHEN t.status = 'pending' AND t.expires_at <= NOW() THEN 'expired' ELSE t.status::TEXT END
+`
+
+func CheckIsDirectNoteMember(noteID, userID uuid.UUID) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM notes_users WHERE note_id = $1 AND user_id = $2)"
+	err := db.DB.QueryRow(query, noteID, userID).Scan(&exists)
+	return exists, err
+}
+
+// CreateNoteTransfer asks toUserID to take over the note, replacing any transfer of the note
+// still waiting for an answer.
+func CreateNoteTransfer(noteID, fromUserID, toUserID uuid.UUID, expiresAt time.Time) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = createNoteTransfer(tx, noteID, fromUserID, toUserID, expiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func createNoteTransfer(
+	tx *sql.Tx,
+	noteID uuid.UUID,
+	fromUserID uuid.UUID,
+	toUserID uuid.UUID,
+	expiresAt time.Time,
+) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE note_transfers SET status = 'revoked', responded_at = NOW()
+		WHERE note_id = $1 AND status = 'pending'
+	`
+	if _, err = tx.Exec(query, noteID); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO note_transfers (id, note_id, from_user_id, to_user_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.Exec(query, id, noteID, fromUserID, toUserID, expiresAt)
+	return err
+}
+
+// requestBulkNoteTransfer asks for every live note owned by the user to be taken over. All of
+// them go to toUserID when it is set, otherwise each shared note goes to its heir and unshared
+// notes stay put. It returns how many transfers were requested.
+func requestBulkNoteTransfer(
+	tx *sql.Tx,
+	fromUserID uuid.UUID,
+	toUserID *uuid.UUID,
+	expiresAt time.Time,
+) (int, error) {
+	query := noteHeirsQuery
+	params := []interface{}{fromUserID}
+	if toUserID != nil {
+		query = "SELECT id, $2::UUID FROM notes WHERE user_id = $1 AND deleted_at IS NULL"
+		params = append(params, *toUserID)
+	}
+
+	transfers, err := queryNoteRecipients(tx, query, params...)
+	if err != nil {
+		return 0, err
+	}
+	for noteID, recipientID := range transfers {
+		if err = createNoteTransfer(tx, noteID, fromUserID, recipientID, expiresAt); err != nil {
+			return 0, err
+		}
+	}
+	return len(transfers), nil
+}
+
+// transferNotesToHeirs hands every shared note of the user straight to its heir. It is used when
+// the account goes away and nobody is left to wait for.
+func transferNotesToHeirs(tx *sql.Tx, fromUserID uuid.UUID) (int, error) {
+	heirs, err := queryNoteRecipients(tx, noteHeirsQuery, fromUserID)
+	if err != nil {
+		return 0, err
+	}
+	for noteID, heirID := range heirs {
+		if err = transferNote(tx, noteID, fromUserID, heirID); err != nil {
+			return 0, err
+		}
+	}
+	return len(heirs), nil
+}
+
+func queryNoteRecipients(tx *sql.Tx, query string, params ...interface{}) (map[uuid.UUID]uuid.UUID, error) {
+	rows, err := tx.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := map[uuid.UUID]uuid.UUID{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var noteID, userID uuid.UUID
+		if err := rows.Scan(&noteID, &userID); err != nil {
+			return nil, err
+		}
+		recipients[noteID] = userID
+	}
+	return recipients, rows.Err()
+}
+
+// transferNote makes toUserID the owner of the note. The recipient's membership row goes away,
+// the previous owner stays on as an editor and the note leaves the previous owner's notebook.
+func transferNote(tx *sql.Tx, noteID, fromUserID, toUserID uuid.UUID) error {
+	query := "UPDATE notes SET user_id = $1, notebook_id = NULL WHERE id = $2"
+	if _, err := tx.Exec(query, toUserID, noteID); err != nil {
+		return err
+	}
+
+	query = "DELETE FROM notes_users WHERE note_id = $1 AND user_id = $2"
+	if _, err := tx.Exec(query, noteID, toUserID); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO notes_users (note_id, user_id, role) VALUES ($1, $2, 'editor')
+		ON CONFLICT (note_id, user_id) DO UPDATE SET role = 'editor'
+	`
+	_, err := tx.Exec(query, noteID, fromUserID)
+	return err
+}
+
+func GetNoteTransfers(userID uuid.UUID) ([]model.NoteTransferResponse, error) {
+	return queryNoteTransfers(
+		"t.to_user_id = $1 AND t.status = 'pending' AND t.expires_at > NOW()",
+		userID,
+	)
+}
+
+// GetSentNoteTransfers lists transfers the user requested with their whole status history.
+func GetSentNoteTransfers(userID uuid.UUID) ([]model.NoteTransferResponse, error) {
+	return queryNoteTransfers("t.from_user_id = $1", userID)
+}
+
+func queryNoteTransfers(where string, userID uuid.UUID) ([]model.NoteTransferResponse, error) {
+	query := `
+		SELECT t.id, n.id, n.title, f.id, f.email, f.name, r.id, r.email, r.name,
+			` + noteTransferStatusColumn + `, t.expires_at, t.responded_at, t.created_at
+		FROM note_transfers t
+		JOIN notes n ON t.note_id = n.id
+		JOIN users f ON t.from_user_id = f.id
+		JOIN users r ON t.to_user_id = r.id
+		WHERE ` + where + `
+		ORDER BY t.created_at DESC
+	`
+	rows, err := db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := []model.NoteTransferResponse{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var t model.NoteTransferResponse
+		if err := rows.Scan(
+			&t.ID,
+			&t.Note.ID,
+			&t.Note.Title,
+			&t.From.ID,
+			&t.From.Email,
+			&t.From.Name,
+			&t.To.ID,
+			&t.To.Email,
+			&t.To.Name,
+			&t.Status,
+			&t.ExpiresAt,
+			&t.RespondedAt,
+			&t.CreatedAt,
+		); err != nil {
+			log.Println(err)
+		}
+		transfers = append(transfers, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// GetNoteTransferByID returns a pending transfer addressed to the user, including expired ones so
+// the caller can tell them apart from unknown ids.
+func GetNoteTransferByID(transferID, userID uuid.UUID) (*model.NoteTransfer, error) {
+	var t model.NoteTransfer
+	query := `
+		SELECT id, note_id, from_user_id, to_user_id, expires_at FROM note_transfers
+		WHERE id = $1 AND to_user_id = $2 AND status = 'pending'
+	`
+	if err := db.DB.
+		QueryRow(query, transferID, userID).
+		Scan(&t.ID, &t.NoteID, &t.FromUserID, &t.ToUserID, &t.ExpiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func ExpireNoteTransfer(transferID uuid.UUID) error {
+	query := "UPDATE note_transfers SET status = 'expired' WHERE id = $1 AND status = 'pending'"
+	_, err := db.DB.Exec(query, transferID)
+	return err
+}
+
+func DeclineNoteTransfer(transferID, userID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE note_transfers SET status = 'declined', responded_at = NOW()
+		WHERE id = $1 AND to_user_id = $2 AND status = 'pending' AND expires_at > NOW()
+	`
+	result, err := db.DB.Exec(query, transferID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// CancelNoteTransfer withdraws a pending transfer. Only its sender or the current note owner may
+// do so.
+func CancelNoteTransfer(transferID, userID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE note_transfers t SET status = 'revoked', responded_at = NOW()
+		FROM notes n
+		WHERE t.note_id = n.id AND t.id = $1 AND (t.from_user_id = $2 OR n.user_id = $2)
+			AND t.status = 'pending'
+	`
+	result, err := db.DB.Exec(query, transferID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// AcceptNoteTransfer completes the transfer. It returns false when the note changed hands or
+// went to the trash since the transfer was requested, in which case the transfer is revoked.
+func AcceptNoteTransfer(t *model.NoteTransfer) (bool, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	var valid bool
+	query := `
+		SELECT EXISTS(SELECT 1 FROM notes WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)
+		FROM note_transfers
+		WHERE id = $3 AND status = 'pending'
+		FOR UPDATE
+	`
+	if err = tx.QueryRow(query, t.NoteID, t.FromUserID, t.ID).Scan(&valid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	status := "accepted"
+	if valid {
+		if err = transferNote(tx, t.NoteID, t.FromUserID, t.ToUserID); err != nil {
+			return false, err
+		}
+	} else {
+		status = "revoked"
+	}
+
+	query = "UPDATE note_transfers SET status = $1, responded_at = NOW() WHERE id = $2"
+	if _, err = tx.Exec(query, status, t.ID); err != nil {
+		return false, err
+	}
+
+	return valid, tx.Commit()
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+// notebookRoleColumn returns a select expression resolving the strongest role the user
+// referenced by userParam inherits on notebook nb from a membership on it or any ancestor.
+func notebookRoleColumn(userParam string) string {
+	return fmt.Sprintf(`(
+		SELECT CASE WHEN bool_or(nbu.role = 'editor') THEN 'editor' ELSE 'viewer' END
+		FROM notebooks_users nbu
+		WHERE nbu.notebook_id = ANY (nb.path) AND nbu.user_id = %s
+		HAVING COUNT(*) > 0
+	)`, userParam)
+}
+
+func CreateNotebook(userID uuid.UUID, body *model.NotebookInput) (*model.Notebook, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	var nb model.Notebook
+	query := `
+		INSERT INTO notebooks (id, user_id, parent_id, name, path)
+		VALUES ($1, $2, $3, $4, COALESCE((SELECT path FROM notebooks WHERE id = $3), '{}') || $1::UUID)
+		RETURNING id, user_id, parent_id, name, created_at, updated_at
+	`
+	err = db.DB.
+		QueryRow(query, id, userID, body.ParentID, body.Name).
+		Scan(&nb.ID, &nb.UserID, &nb.ParentID, &nb.Name, &nb.CreatedAt, &nb.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &nb, nil
+}
+
+// CheckNotebookParentValid reports whether parentID is a notebook owned by the user that can
+// hold notebookID without creating a cycle. Pass a nil notebookID for new notebooks.
+func CheckNotebookParentValid(parentID uuid.UUID, notebookID *uuid.UUID, userID uuid.UUID) (bool, error) {
+	var valid bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM notebooks
+			WHERE id = $1 AND user_id = $2 AND ($3::UUID IS NULL OR NOT ($3 = ANY (path)))
+		)
+	`
+	err := db.DB.QueryRow(query, parentID, userID, notebookID).Scan(&valid)
+	return valid, err
+}
+
+func CheckIsNotebookOwner(notebookID, userID uuid.UUID) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM notebooks WHERE id = $1 AND user_id = $2)"
+	err := db.DB.QueryRow(query, notebookID, userID).Scan(&exists)
+	return exists, err
+}
+
+// GetNotebooks returns notebooks the user owns or can access through sharing, as a flat list
+// ordered by depth so parents come before their children.
+func GetNotebooks(userID uuid.UUID) ([]model.Notebook, error) {
+	query := `
+		SELECT nb.id, nb.user_id, nb.parent_id, nb.name,
+			CASE WHEN nb.user_id = $1 THEN NULL ELSE ` + notebookRoleColumn("$1") + ` END,
+			nb.created_at, nb.updated_at
+		FROM notebooks nb
+		WHERE nb.user_id = $1
+			OR nb.path && ARRAY(SELECT notebook_id FROM notebooks_users WHERE user_id = $1)
+		ORDER BY cardinality(nb.path), nb.name
+	`
+	rows, err := db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	notebooks := []model.Notebook{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var nb model.Notebook
+		if err := rows.Scan(
+			&nb.ID,
+			&nb.UserID,
+			&nb.ParentID,
+			&nb.Name,
+			&nb.Role,
+			&nb.CreatedAt,
+			&nb.UpdatedAt,
+		); err != nil {
+			log.Println(err)
+		}
+		notebooks = append(notebooks, nb)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return notebooks, nil
+}
+
+func GetNotebookByID(notebookID, userID uuid.UUID) (*model.NotebookDetail, error) {
+	var nb model.NotebookDetail
+	query := `
+		SELECT nb.id, nb.user_id, nb.parent_id, nb.name,
+			CASE WHEN nb.user_id = $2 THEN NULL ELSE ` + notebookRoleColumn("$2") + ` END AS role,
+			u.id, u.email, u.name, nb.created_at, nb.updated_at
+		FROM notebooks nb
+		JOIN users u ON nb.user_id = u.id
+		WHERE nb.id = $1
+	`
+	err := db.DB.
+		QueryRow(query, notebookID, userID).
+		Scan(&nb.ID, &nb.UserID, &nb.ParentID, &nb.Name, &nb.Role, &nb.Owner.ID, &nb.Owner.Email, &nb.Owner.Name, &nb.CreatedAt, &nb.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if nb.UserID != userID && nb.Role == nil {
+		return nil, nil
+	}
+
+	children := []model.Notebook{}
+	query = `
+		SELECT id, user_id, parent_id, name, created_at, updated_at
+		FROM notebooks
+		WHERE parent_id = $1
+		ORDER BY name
+	`
+	rows, err := db.DB.Query(query, notebookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c model.Notebook
+		if err := rows.Scan(&c.ID, &c.UserID, &c.ParentID, &c.Name, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			log.Println(err)
+		}
+		c.Role = nb.Role
+		children = append(children, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	members := []model.NoteMember{}
+	query = `
+		SELECT u.id, u.email, u.name, nbu.role, nbu.created_at
+		FROM notebooks_users nbu
+		JOIN users u ON nbu.user_id = u.id
+		WHERE nbu.notebook_id = $1
+	`
+	memberRows, err := db.DB.Query(query, notebookID)
+	if err != nil {
+		return nil, err
+	}
+	defer memberRows.Close()
+	for memberRows.Next() {
+		var m model.NoteMember
+		if err := memberRows.Scan(&m.ID, &m.Email, &m.Name, &m.Role, &m.CreatedAt); err != nil {
+			log.Println(err)
+		}
+		members = append(members, m)
+	}
+	if err = memberRows.Err(); err != nil {
+		return nil, err
+	}
+
+	nb.Children = children
+	nb.Members = members
+	return &nb, nil
+}
+
+// UpdateNotebook renames the notebook and moves it, together with its subtree, under the new
+// parent. The parent must be validated with CheckNotebookParentValid beforehand.
+func UpdateNotebook(notebookID, userID uuid.UUID, body *model.NotebookInput) (bool, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	query := "UPDATE notebooks SET name = $1, parent_id = $2 WHERE id = $3 AND user_id = $4"
+	result, err := tx.Exec(query, body.Name, body.ParentID, notebookID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	query = `
+		WITH old AS (
+			SELECT path FROM notebooks WHERE id = $1
+		), new AS (
+			SELECT COALESCE((SELECT path FROM notebooks WHERE id = $2), '{}') || $1::UUID AS path
+		)
+		UPDATE notebooks nb
+		SET path = new.path || nb.path[cardinality(old.path) + 1:]
+		FROM old, new
+		WHERE nb.path @> ARRAY[$1::UUID]
+	`
+	if _, err = tx.Exec(query, notebookID, body.ParentID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func DeleteNotebook(notebookID, userID uuid.UUID) (bool, error) {
+	query := "DELETE FROM notebooks WHERE id = $1 AND user_id = $2"
+	result, err := db.DB.Exec(query, notebookID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+func AddNotebookMember(notebookID, userID uuid.UUID, role string) error {
+	query := `
+		INSERT INTO notebooks_users (notebook_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (notebook_id, user_id) DO UPDATE SET role = EXCLUDED.role
+	`
+	_, err := db.DB.Exec(query, notebookID, userID, role)
+	return err
+}
+
+func UpdateNotebookMemberRole(notebookID, memberID uuid.UUID, role string) (bool, error) {
+	query := "UPDATE notebooks_users SET role = $1 WHERE notebook_id = $2 AND user_id = $3"
+	result, err := db.DB.Exec(query, role, notebookID, memberID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+func RemoveNotebookMember(notebookID, memberID uuid.UUID) (bool, error) {
+	query := "DELETE FROM notebooks_users WHERE notebook_id = $1 AND user_id = $2"
+	result, err := db.DB.Exec(query, notebookID, memberID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
@@ -17,57 +17,106 @@ func CreateNote(title string, content *string, userID uuid.UUID) error {
 	if err != nil {
 		return err
 	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
 	query := "INSERT INTO notes (id, title, content, user_id) VALUES ($1, $2, $3, $4)"
-	_, err = db.DB.Exec(query, id, title, content, userID)
-	return err
+	if _, err = tx.Exec(query, id, title, content, userID); err != nil {
+		return err
+	}
+
+	if err = createNoteRevision(tx, id, userID, title, content); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func GetNotes(userID uuid.UUID, opts *model.NoteQuery) ([]model.NoteResponse, int, error) {
 	notes := []model.NoteResponse{}
 
-	queryBuilder := strings.Builder{}
-	countQueryBuilder := strings.Builder{}
-
-	queryBuilder.WriteString(
-		"SELECT n.id, n.user_id, n.title, nu.role, n.created_at, n.updated_at FROM notes n LEFT JOIN notes_users nu ON n.id = nu.note_id AND nu.user_id = $1 WHERE (n.user_id = $1 OR nu.user_id = $1)",
-	)
-	countQueryBuilder.WriteString(
-		"SELECT COUNT(*) FROM notes n LEFT JOIN notes_users nu ON n.id = nu.note_id AND nu.user_id = $1 WHERE (n.user_id = $1 OR nu.user_id = $1)",
+	columns := "n.id, n.user_id, n.notebook_id, n.title, nu.role, COALESCE(s.pinned, FALSE), COALESCE(s.archived, FALSE), COALESCE(s.favorite, FALSE), n.created_at, n.updated_at, " + noteTagsColumn("$1")
+	fromBuilder := strings.Builder{}
+	fromBuilder.WriteString(
+		" FROM notes n LEFT JOIN note_access nu ON n.id = nu.note_id AND nu.user_id = $1 LEFT JOIN note_states s ON n.id = s.note_id AND s.user_id = $1 WHERE (n.user_id = $1 OR nu.user_id = $1) AND n.deleted_at IS NULL",
 	)
 	params := []interface{}{userID}
+	orderBy := fmt.Sprintf("COALESCE(s.pinned, FALSE) DESC, n.%s %s", opts.Sort, opts.Order)
 
-	if opts.Query != "" {
-		str := fmt.Sprintf(" AND n.title ILIKE $%d", len(params)+1)
-		queryBuilder.WriteString(str)
-		countQueryBuilder.WriteString(str)
+	fullText := opts.Query != "" && opts.Mode == "fulltext"
+	if fullText {
+		tsQuery := fmt.Sprintf("websearch_to_tsquery('simple', $%d)", len(params)+1)
+		columns += fmt.Sprintf(
+			", ts_headline('simple', COALESCE(n.content, n.title), %s, 'MaxFragments=2, MaxWords=30, MinWords=10')",
+			tsQuery,
+		)
+		fromBuilder.WriteString(" AND n.search_vector @@ " + tsQuery)
+		orderBy = fmt.Sprintf("ts_rank(n.search_vector, %s) DESC, %s", tsQuery, orderBy)
+		params = append(params, opts.Query)
+	} else if opts.Query != "" {
+		fromBuilder.WriteString(fmt.Sprintf(" AND n.title ILIKE $%d", len(params)+1))
 		params = append(params, "%"+opts.Query+"%")
 	}
 
+	if names := uniqueTagNames(opts.Tags); len(names) > 0 {
+		tagFilter := fmt.Sprintf(
+			"SELECT COUNT(DISTINCT t.name) FROM notes_tags nt JOIN tags t ON nt.tag_id = t.id WHERE nt.note_id = n.id AND t.user_id = $1 AND t.name = ANY($%d::CITEXT[])",
+			len(params)+1,
+		)
+		params = append(params, names)
+		if opts.TagMatch == "all" {
+			fromBuilder.WriteString(fmt.Sprintf(" AND (%s) = $%d", tagFilter, len(params)+1))
+			params = append(params, len(names))
+		} else {
+			fromBuilder.WriteString(fmt.Sprintf(" AND (%s) > 0", tagFilter))
+		}
+	}
+
+	if opts.Notebook == "none" {
+		fromBuilder.WriteString(" AND n.notebook_id IS NULL")
+	} else if opts.Notebook != "" {
+		fromBuilder.WriteString(fmt.Sprintf(" AND n.notebook_id = $%d", len(params)+1))
+		params = append(params, opts.Notebook)
+	}
+
+	states := []struct{ column, value string }{
+		{"pinned", opts.Pinned},
+		{"archived", opts.Archived},
+		{"favorite", opts.Favorite},
+	}
+	for _, state := range states {
+		if state.value == "true" || state.value == "false" {
+			fromBuilder.WriteString(
+				fmt.Sprintf(" AND COALESCE(s.%s, FALSE) = %s", state.column, state.value),
+			)
+		}
+	}
+
 	if opts.Role != "" {
 		if opts.Role == "owner" {
-			str := fmt.Sprintf(" AND n.user_id = $%d", len(params)+1)
-			queryBuilder.WriteString(str)
-			countQueryBuilder.WriteString(str)
+			fromBuilder.WriteString(fmt.Sprintf(" AND n.user_id = $%d", len(params)+1))
 			params = append(params, userID)
 		} else {
-			str := fmt.Sprintf(" AND nu.role = $%d", len(params)+1)
-			queryBuilder.WriteString(str)
-			countQueryBuilder.WriteString(str)
+			fromBuilder.WriteString(fmt.Sprintf(" AND nu.role = $%d", len(params)+1))
 			params = append(params, opts.Role)
 		}
 	}
 
-	queryBuilder.WriteString(
-		fmt.Sprintf(
-			" ORDER BY n.%s %s LIMIT %d OFFSET %d",
-			opts.Sort,
-			opts.Order,
-			opts.PageSize,
-			(opts.Page-1)*opts.PageSize,
-		),
+	from := fromBuilder.String()
+	query := fmt.Sprintf(
+		"SELECT %s%s ORDER BY %s LIMIT %d OFFSET %d",
+		columns,
+		from,
+		orderBy,
+		opts.PageSize,
+		(opts.Page-1)*opts.PageSize,
 	)
 
-	query := queryBuilder.String()
 	rows, err := db.DB.Query(query, params...)
 	if err != nil {
 		log.Println("Error querying notes:", err)
@@ -76,8 +125,11 @@ func GetNotes(userID uuid.UUID, opts *model.NoteQuery) ([]model.NoteResponse, in
 	defer rows.Close()
 	for rows.Next() {
 		var n model.NoteResponse
-		err := rows.Scan(&n.ID, &n.UserID, &n.Title, &n.Role, &n.CreatedAt, &n.UpdatedAt)
-		if err != nil {
+		dest := []interface{}{&n.ID, &n.UserID, &n.NotebookID, &n.Title, &n.Role, &n.Pinned, &n.Archived, &n.Favorite, &n.CreatedAt, &n.UpdatedAt, &n.Tags}
+		if fullText {
+			dest = append(dest, &n.Snippet)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			log.Println("Error scanning note:", err)
 		}
 		notes = append(notes, n)
@@ -88,7 +140,7 @@ func GetNotes(userID uuid.UUID, opts *model.NoteQuery) ([]model.NoteResponse, in
 	}
 
 	var total int
-	countQuery := countQueryBuilder.String()
+	countQuery := "SELECT COUNT(*)" + from
 	if err = db.DB.QueryRow(countQuery, params...).Scan(&total); err != nil {
 		log.Println("Error counting notes:", err)
 		return nil, 0, err
@@ -100,15 +152,17 @@ func GetNotes(userID uuid.UUID, opts *model.NoteQuery) ([]model.NoteResponse, in
 func GetNoteByID(noteID, userID uuid.UUID) (*model.NoteDetail, error) {
 	var n model.NoteDetail
 	query := `
-		SELECT n.id, n.title, n.content, nu.role, u.id AS owner_id, u.email, u.name, n.created_at, n.updated_at
+		SELECT n.id, n.title, n.content, nu.role, n.notebook_id, u.id AS owner_id, u.email, u.name, n.version, ` + noteTagsColumn("$2") + `,
+			COALESCE(s.pinned, FALSE), COALESCE(s.archived, FALSE), COALESCE(s.favorite, FALSE), n.created_at, n.updated_at
 		FROM notes n
 		JOIN users u ON n.user_id = u.id
-		LEFT JOIN notes_users nu ON n.id = nu.note_id AND nu.user_id = $2
-		WHERE n.id = $1 AND (n.user_id = $2 OR nu.user_id = $2)
+		LEFT JOIN note_access nu ON n.id = nu.note_id AND nu.user_id = $2
+		LEFT JOIN note_states s ON n.id = s.note_id AND s.user_id = $2
+		WHERE n.id = $1 AND (n.user_id = $2 OR nu.user_id = $2) AND n.deleted_at IS NULL
 	`
 	err := db.DB.
 		QueryRow(query, noteID, userID).
-		Scan(&n.ID, &n.Title, &n.Content, &n.Role, &n.Owner.ID, &n.Owner.Email, &n.Owner.Name, &n.CreatedAt, &n.UpdatedAt)
+		Scan(&n.ID, &n.Title, &n.Content, &n.Role, &n.NotebookID, &n.Owner.ID, &n.Owner.Email, &n.Owner.Name, &n.Version, &n.Tags, &n.Pinned, &n.Archived, &n.Favorite, &n.CreatedAt, &n.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -145,31 +199,133 @@ func GetNoteByID(noteID, userID uuid.UUID) (*model.NoteDetail, error) {
 
 func CheckNoteExists(id, userID uuid.UUID) (bool, error) {
 	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM notes WHERE id = $1 AND user_id = $2)"
+	query := "SELECT EXISTS(SELECT 1 FROM notes WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)"
 	err := db.DB.QueryRow(query, id, userID).Scan(&exists)
 	return exists, err
 }
 
-func UpdateNoteByID(body *model.NoteInput, noteID uuid.UUID, userID uuid.UUID) (bool, error) {
+// GetNoteRole returns "owner", "editor" or "viewer" for the user on the note, or nil when the
+// user has no access to it.
+func GetNoteRole(noteID, userID uuid.UUID) (*string, error) {
+	var role string
+	query := `
+		SELECT CASE WHEN n.user_id = $2 THEN 'owner' ELSE nu.role::TEXT END
+		FROM notes n
+		LEFT JOIN note_access nu ON n.id = nu.note_id AND nu.user_id = $2
+		WHERE n.id = $1 AND (n.user_id = $2 OR nu.user_id = $2) AND n.deleted_at IS NULL
+	`
+	if err := db.DB.QueryRow(query, noteID, userID).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// UpdateNoteByID updates the note when the user is its owner or an editor and returns the new
+// version. When version is not nil the update only applies if it matches the stored version.
+func UpdateNoteByID(
+	body *model.NoteInput,
+	noteID uuid.UUID,
+	userID uuid.UUID,
+	version *int,
+) (*int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var newVersion int
 	query := `
-		WITH notes_to_update AS (
-			SELECT n.id
-			FROM notes n
-			LEFT JOIN notes_users nu ON nu.note_id = n.id
-			WHERE n.id = $3
-				AND (
-					n.user_id = $4
-					OR (
-					    nu.user_id = $4
-						AND nu.role = 'editor'
-					)
+		UPDATE notes n
+		SET title = $1, content = $2, version = version + 1
+		WHERE n.id = $3
+			AND n.deleted_at IS NULL
+			AND ($5::INTEGER IS NULL OR n.version = $5)
+			AND (
+				n.user_id = $4
+				OR EXISTS(
+					SELECT 1 FROM note_access
+					WHERE note_id = n.id AND user_id = $4 AND role = 'editor'
 				)
-		)
-		UPDATE notes
-		SET title = $1, content = $2
-		WHERE id IN (SELECT id FROM notes_to_update)
+			)
+		RETURNING version
 	`
-	result, err := db.DB.Exec(query, body.Title, body.Content, noteID, userID)
+	err = tx.
+		QueryRow(query, body.Title, body.Content, noteID, userID, version).
+		Scan(&newVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err = createNoteRevision(tx, noteID, userID, body.Title, body.Content); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &newVersion, nil
+}
+
+// updateNoteContent replaces only the note content, keeping the title as it is, and records a
+// revision authored by userID. The row is locked while merge computes the new content from the
+// stored content and version, so a save made elsewhere in the meantime is never overwritten. It
+// returns the new version, or nil when the note is gone.
+func updateNoteContent(
+	noteID uuid.UUID,
+	userID uuid.UUID,
+	merge func(stored *string, version int) (*string, error),
+) (*int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var title string
+	var stored *string
+	var version int
+	query := "SELECT title, content, version FROM notes WHERE id = $1 AND deleted_at IS NULL FOR UPDATE"
+	if err = tx.QueryRow(query, noteID).Scan(&title, &stored, &version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	content, err := merge(stored, version)
+	if err != nil {
+		return nil, err
+	}
+
+	query = "UPDATE notes SET content = $1, version = version + 1 WHERE id = $2 RETURNING version"
+	if err = tx.QueryRow(query, content, noteID).Scan(&version); err != nil {
+		return nil, err
+	}
+
+	if err = createNoteRevision(tx, noteID, userID, title, content); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// MoveNote moves a note owned by the user into one of the user's notebooks, or out of any
+// notebook when notebookID is nil.
+func MoveNote(noteID, userID uuid.UUID, notebookID *uuid.UUID) (bool, error) {
+	query := "UPDATE notes SET notebook_id = $1 WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL"
+	result, err := db.DB.Exec(query, notebookID, noteID, userID)
 	if err != nil {
 		return false, err
 	}
@@ -183,9 +339,14 @@ func UpdateNoteByID(body *model.NoteInput, noteID uuid.UUID, userID uuid.UUID) (
 	return true, nil
 }
 
-func DeleteNoteByID(id, userID uuid.UUID) (bool, error) {
-	query := "DELETE FROM notes WHERE id = $1 AND user_id = $2"
-	result, err := db.DB.Exec(query, id, userID)
+// DeleteNoteByID moves the note owned by the user to the trash. When version is not nil the note
+// is only trashed if it matches the stored version.
+func DeleteNoteByID(id, userID uuid.UUID, version *int) (bool, error) {
+	query := `
+		UPDATE notes SET deleted_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND ($3::INTEGER IS NULL OR version = $3)
+	`
+	result, err := db.DB.Exec(query, id, userID, version)
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+func CreateOIDCState(state string, s *model.OIDCState, expiresAt time.Time) error {
+	query := "DELETE FROM oidc_states WHERE expires_at <= NOW()"
+	if _, err := db.DB.Exec(query); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO oidc_states (state_hash, nonce, code_verifier, remember, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := db.DB.Exec(query, HashToken(state), s.Nonce, s.CodeVerifier, s.Remember, expiresAt)
+	return err
+}
+
+// ConsumeOIDCState deletes the state so a callback can only be completed once.
+func ConsumeOIDCState(state string) (*model.OIDCState, error) {
+	var s model.OIDCState
+	query := `
+		DELETE FROM oidc_states WHERE state_hash = $1 AND expires_at > NOW()
+		RETURNING nonce, code_verifier, remember
+	`
+	if err := db.DB.QueryRow(query, HashToken(state)).Scan(&s.Nonce, &s.CodeVerifier, &s.Remember); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func GetUserIDByIdentity(issuer, subject string) (*uuid.UUID, error) {
+	var id uuid.UUID
+	query := "SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2"
+	if err := db.DB.QueryRow(query, issuer, subject).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &id, nil
+}
+
+// LinkIdentity links a provider account to an existing user with the same email. The provider
+// has verified the address, so the user's email is marked verified as well.
+//
+// When the local account never verified the address, whoever set it up may not own the mailbox.
+// Its password is then replaced with resetPasswordHash and every other way in (sessions, API
+// tokens, passkeys, two-factor) is removed, so a pre-registered account can't be kept as a back
+// door. It reports whether that happened.
+func LinkIdentity(userID uuid.UUID, issuer, subject, email, resetPasswordHash string) (bool, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	var verified bool
+	query := "SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1 AND email = $2 FOR UPDATE"
+	if err = tx.QueryRow(query, userID, email).Scan(&verified); err != nil {
+		return false, err
+	}
+
+	if !verified {
+		query = `
+			UPDATE users
+			SET password = $2, pending_email = NULL, totp_secret = NULL, totp_enabled_at = NULL,
+				totp_last_step = NULL
+			WHERE id = $1
+		`
+		if _, err = tx.Exec(query, userID, resetPasswordHash); err != nil {
+			return false, err
+		}
+
+		for _, table := range []string{
+			"sessions",
+			"api_tokens",
+			"webauthn_credentials",
+			"totp_recovery_codes",
+			"login_challenges",
+		} {
+			if _, err = tx.Exec("DELETE FROM "+table+" WHERE user_id = $1", userID); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	query = `
+		INSERT INTO user_identities (id, user_id, issuer, subject, email) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (issuer, subject) DO NOTHING
+	`
+	if _, err = tx.Exec(query, id, userID, issuer, subject, email); err != nil {
+		return false, err
+	}
+
+	query = `
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = $1 AND email = $2
+	`
+	if _, err = tx.Exec(query, userID, email); err != nil {
+		return false, err
+	}
+
+	return !verified, tx.Commit()
+}
+
+// CreateOIDCUser creates a verified user for a provider account. The password hash is of a
+// random secret, so the account can only sign in through the provider until a password is set
+// with the reset flow.
+func CreateOIDCUser(email string, name *string, hashedPassword, issuer, subject string) (uuid.UUID, error) {
+	userID, err := uuid.NewV7()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	identityID, err := uuid.NewV7()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO users (id, name, email, password, email_verified_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`
+	if _, err = tx.Exec(query, userID, name, email, hashedPassword); err != nil {
+		return uuid.Nil, err
+	}
+
+	query = "INSERT INTO user_identities (id, user_id, issuer, subject, email) VALUES ($1, $2, $3, $4, $5)"
+	if _, err = tx.Exec(query, identityID, userID, issuer, subject, email); err != nil {
+		return uuid.Nil, err
+	}
+
+	return userID, tx.Commit()
+}
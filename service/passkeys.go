@@ -0,0 +1,234 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/webauthn"
+	"github.com/google/uuid"
+)
+
+// CreateWebAuthnChallenge stores a challenge issued for a registration or login ceremony. The
+// user is nil for logins with discoverable credentials where the user isn't known yet.
+func CreateWebAuthnChallenge(challenge string, userID *uuid.UUID, ceremony string, expiresAt time.Time) error {
+	query := "DELETE FROM webauthn_challenges WHERE expires_at <= NOW()"
+	if _, err := db.DB.Exec(query); err != nil {
+		return err
+	}
+
+	query = "INSERT INTO webauthn_challenges (challenge, user_id, ceremony, expires_at) VALUES ($1, $2, $3, $4)"
+	_, err := db.DB.Exec(query, challenge, userID, ceremony, expiresAt)
+	return err
+}
+
+// ConsumeWebAuthnChallenge deletes the challenge so it can only be answered once. It returns nil
+// when the challenge is unknown, expired or was issued for another ceremony.
+func ConsumeWebAuthnChallenge(challenge string, ceremony string) (*model.WebAuthnChallenge, error) {
+	var c model.WebAuthnChallenge
+	query := `
+		DELETE FROM webauthn_challenges
+		WHERE challenge = $1 AND ceremony = $2 AND expires_at > NOW()
+		RETURNING user_id
+	`
+	if err := db.DB.QueryRow(query, challenge, ceremony).Scan(&c.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetPasskeyDescriptors returns the user's credentials for allow and exclude lists.
+func GetPasskeyDescriptors(userID uuid.UUID) ([]webauthn.CredentialDescriptor, error) {
+	query := "SELECT credential_id, array_to_json(transports) FROM webauthn_credentials WHERE user_id = $1"
+	rows, err := db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptors := []webauthn.CredentialDescriptor{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var credentialID, transports []byte
+		if err := rows.Scan(&credentialID, &transports); err != nil {
+			log.Println(err)
+			continue
+		}
+		var t []string
+		json.Unmarshal(transports, &t)
+		descriptors = append(descriptors, webauthn.Descriptor(credentialID, t))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return descriptors, nil
+}
+
+func CheckPasskeyCredentialExists(credentialID []byte) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM webauthn_credentials WHERE credential_id = $1)"
+	err := db.DB.QueryRow(query, credentialID).Scan(&exists)
+	return exists, err
+}
+
+func CreatePasskey(userID uuid.UUID, name string, cred *webauthn.Credential, transports []string) (*model.Passkey, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	if transports == nil {
+		transports = []string{}
+	}
+
+	var p model.Passkey
+	var t []byte
+	query := `
+		INSERT INTO webauthn_credentials (
+			id, user_id, credential_id, public_key, sign_count, aaguid, transports,
+			backup_eligible, backed_up, name
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7::TEXT[], $8, $9, $10)
+		RETURNING id, name, array_to_json(transports), backup_eligible, backed_up, last_used_at,
+			created_at, updated_at
+	`
+	err = db.DB.
+		QueryRow(
+			query,
+			id,
+			userID,
+			cred.ID,
+			cred.PublicKey,
+			int64(cred.SignCount),
+			cred.AAGUID,
+			transports,
+			cred.BackupEligible,
+			cred.BackedUp,
+			name,
+		).
+		Scan(&p.ID, &p.Name, &t, &p.BackupEligible, &p.BackedUp, &p.LastUsedAt, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(t, &p.Transports); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func GetPasskeyByCredentialID(credentialID []byte) (*model.PasskeyCredential, error) {
+	var p model.PasskeyCredential
+	var signCount int64
+	query := "SELECT id, user_id, public_key, sign_count FROM webauthn_credentials WHERE credential_id = $1"
+	err := db.DB.
+		QueryRow(query, credentialID).
+		Scan(&p.ID, &p.UserID, &p.PublicKey, &signCount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	p.SignCount = uint32(signCount)
+	return &p, nil
+}
+
+// UpdatePasskeyUsage stores the new sign count after a successful login. The update only
+// applies if the stored count hasn't changed since it was read, so two concurrent logins with a
+// cloned authenticator can't both succeed.
+func UpdatePasskeyUsage(id uuid.UUID, oldSignCount, signCount uint32) (bool, error) {
+	query := `
+		UPDATE webauthn_credentials SET sign_count = $1, last_used_at = NOW()
+		WHERE id = $2 AND sign_count = $3
+	`
+	result, err := db.DB.Exec(query, int64(signCount), id, int64(oldSignCount))
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+func GetPasskeys(userID uuid.UUID) ([]model.Passkey, error) {
+	query := `
+		SELECT id, name, array_to_json(transports), backup_eligible, backed_up, last_used_at,
+			created_at, updated_at
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+	rows, err := db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys := []model.Passkey{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var p model.Passkey
+		var t []byte
+		if err := rows.Scan(
+			&p.ID,
+			&p.Name,
+			&t,
+			&p.BackupEligible,
+			&p.BackedUp,
+			&p.LastUsedAt,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+		); err != nil {
+			log.Println(err)
+		}
+		json.Unmarshal(t, &p.Transports)
+		passkeys = append(passkeys, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return passkeys, nil
+}
+
+func RenamePasskey(id, userID uuid.UUID, name string) (bool, error) {
+	query := "UPDATE webauthn_credentials SET name = $1 WHERE id = $2 AND user_id = $3"
+	result, err := db.DB.Exec(query, name, id, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+func DeletePasskey(id, userID uuid.UUID) (bool, error) {
+	query := "DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2"
+	result, err := db.DB.Exec(query, id, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
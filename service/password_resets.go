@@ -0,0 +1,84 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/google/uuid"
+)
+
+// CreatePasswordResetToken replaces any unused token of the user with a new one and returns the
+// plain token to be emailed.
+func CreatePasswordResetToken(userID uuid.UUID, expiresAt time.Time) (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	token, hash, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+
+	defer tx.Rollback()
+
+	query := "DELETE FROM password_reset_tokens WHERE user_id = $1 AND used_at IS NULL"
+	if _, err = tx.Exec(query, userID); err != nil {
+		return "", err
+	}
+
+	query = "INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)"
+	if _, err = tx.Exec(query, id, userID, hash, expiresAt); err != nil {
+		return "", err
+	}
+
+	return token, tx.Commit()
+}
+
+// ResetPassword consumes the token, sets the new password and signs the user out everywhere.
+// It returns false when the token is unknown, used or expired.
+func ResetPassword(token string, hashedPassword string) (bool, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	query := `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`
+	if err = tx.QueryRow(query, HashToken(token)).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	query = "UPDATE users SET password = $1, password_reset_required = FALSE WHERE id = $2"
+	if _, err = tx.Exec(query, hashedPassword, userID); err != nil {
+		return false, err
+	}
+
+	query = "DELETE FROM sessions WHERE user_id = $1"
+	if _, err = tx.Exec(query, userID); err != nil {
+		return false, err
+	}
+
+	// Proving control of the email also lifts a lockout from failed sign-ins.
+	query = "DELETE FROM login_throttles WHERE key = 'account:' || lower((SELECT email FROM users WHERE id = $1))"
+	if _, err = tx.Exec(query, userID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
@@ -3,33 +3,89 @@ package service
 import (
 	"database/sql"
 	"errors"
+	"log"
 	"time"
 
+	"github.com/amiftachulh/notez-api/config"
 	"github.com/amiftachulh/notez-api/db"
 	"github.com/amiftachulh/notez-api/model"
 	"github.com/google/uuid"
 )
 
-func CreateSession(sessionID string, userID uuid.UUID, expiresAt time.Time) error {
-	query := "INSERT INTO sessions (id, user_id, expires_at) VALUES ($1, $2, $3)"
-	if _, err := db.DB.Exec(query, sessionID, userID, expiresAt); err != nil {
-		return err
+const sessionUserAgentMaxLength = 512
+
+// SessionIdleTimeout returns how long a session stays valid without requests.
+func SessionIdleTimeout(remember bool) time.Duration {
+	if remember {
+		return config.SessionRememberIdleTimeout
+	}
+	return config.SessionIdleTimeout
+}
+
+// SessionExpiry returns when a session used now expires: after the idle timeout, but never
+// later than its absolute expiry.
+func SessionExpiry(remember bool, absoluteExpiresAt time.Time) time.Time {
+	expiresAt := time.Now().Add(SessionIdleTimeout(remember)).Truncate(time.Microsecond)
+	if expiresAt.After(absoluteExpiresAt) {
+		return absoluteExpiresAt
+	}
+	return expiresAt
+}
+
+// CreateSession stores a new session and returns when it expires and its CSRF token.
+func CreateSession(sessionID string, userID uuid.UUID, remember bool, ipAddress, userAgent string) (time.Time, string, error) {
+	publicID, err := uuid.NewV7()
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	csrfToken, _, err := GenerateToken()
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	if len(userAgent) > sessionUserAgentMaxLength {
+		userAgent = userAgent[:sessionUserAgentMaxLength]
+	}
+
+	absoluteExpiresAt := time.Now().Add(config.SessionMaxLifetime).Truncate(time.Microsecond)
+	expiresAt := SessionExpiry(remember, absoluteExpiresAt)
+
+	query := `
+		INSERT INTO sessions (
+			id, public_id, user_id, remember, expires_at, absolute_expires_at, ip_address, user_agent,
+			csrf_token
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9)
+	`
+	_, err = db.DB.Exec(
+		query,
+		sessionID,
+		publicID,
+		userID,
+		remember,
+		expiresAt,
+		absoluteExpiresAt,
+		ipAddress,
+		userAgent,
+		csrfToken,
+	)
+	if err != nil {
+		return time.Time{}, "", err
 	}
-	return nil
+	return expiresAt, csrfToken, nil
 }
 
 func GetUserBySession(sessionID string) (*model.AuthUser, error) {
 	var u model.AuthUser
 	query := `
-		SELECT u.id, u.name, u.email, u.role, u.created_at, u.updated_at, s.expires_at
+		SELECT u.id, u.name, u.email, u.email_verified_at, u.pending_email, u.totp_enabled_at IS NOT NULL, u.role, u.created_at, u.updated_at, s.expires_at, s.public_id, s.csrf_token
 		FROM sessions s
 		JOIN users u
 		ON s.user_id = u.id
-		WHERE s.id = $1 AND s.expires_at > now()
+		WHERE s.id = $1 AND s.expires_at > now() AND u.disabled_at IS NULL
 	`
 	if err := db.DB.
 		QueryRow(query, sessionID).
-		Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt, &u.ExpiresAt); err != nil {
+		Scan(&u.ID, &u.Name, &u.Email, &u.EmailVerifiedAt, &u.PendingEmail, &u.TwoFactorEnabled, &u.Role, &u.CreatedAt, &u.UpdatedAt, &u.ExpiresAt, &u.SessionID, &u.CSRFToken); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -53,3 +109,68 @@ func DeleteSession(sessionID string) (bool, error) {
 	}
 	return true, nil
 }
+
+// GetSessions returns the user's active sessions, most recently used first. The current flag is
+// set by the caller.
+func GetSessions(userID uuid.UUID) ([]model.Session, error) {
+	query := `
+		SELECT public_id, ip_address, user_agent, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`
+	rows, err := db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []model.Session{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var s model.Session
+		if err := rows.Scan(
+			&s.ID,
+			&s.IPAddress,
+			&s.UserAgent,
+			&s.CreatedAt,
+			&s.LastSeenAt,
+			&s.ExpiresAt,
+		); err != nil {
+			log.Println(err)
+		}
+		sessions = append(sessions, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession deletes a session of the user by its public ID.
+func RevokeSession(publicID, userID uuid.UUID) (bool, error) {
+	query := "DELETE FROM sessions WHERE public_id = $1 AND user_id = $2"
+	result, err := db.DB.Exec(query, publicID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+// RevokeOtherSessions deletes every session of the user except the current one and returns how
+// many were deleted.
+func RevokeOtherSessions(userID, currentPublicID uuid.UUID) (int64, error) {
+	query := "DELETE FROM sessions WHERE user_id = $1 AND public_id <> $2"
+	result, err := db.DB.Exec(query, userID, currentPublicID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
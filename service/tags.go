@@ -0,0 +1,163 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+// noteTagsColumn returns a select expression aggregating the tags that the user referenced by
+// userParam attached to note n.
+func noteTagsColumn(userParam string) string {
+	return fmt.Sprintf(`COALESCE((
+		SELECT json_agg(json_build_object('id', t.id, 'name', t.name) ORDER BY t.name)
+		FROM notes_tags nt
+		JOIN tags t ON nt.tag_id = t.id
+		WHERE nt.note_id = n.id AND t.user_id = %s
+	), '[]')`, userParam)
+}
+
+func CheckTagNameExists(userID uuid.UUID, name string) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM tags WHERE user_id = $1 AND name = $2)"
+	err := db.DB.QueryRow(query, userID, name).Scan(&exists)
+	return exists, err
+}
+
+func CreateTag(userID uuid.UUID, name string) (*model.Tag, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	var t model.Tag
+	query := `
+		INSERT INTO tags (id, user_id, name) VALUES ($1, $2, $3)
+		RETURNING id, name, created_at, updated_at
+	`
+	err = db.DB.
+		QueryRow(query, id, userID, name).
+		Scan(&t.ID, &t.Name, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func GetTags(userID uuid.UUID) ([]model.Tag, error) {
+	query := `
+		SELECT t.id, t.name, COUNT(nt.note_id), t.created_at, t.updated_at
+		FROM tags t
+		LEFT JOIN notes_tags nt ON nt.tag_id = t.id
+		WHERE t.user_id = $1
+		GROUP BY t.id
+		ORDER BY t.name
+	`
+	rows, err := db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []model.Tag{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var t model.Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.NoteCount, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			log.Println(err)
+		}
+		tags = append(tags, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func RenameTag(id, userID uuid.UUID, name string) (*model.Tag, error) {
+	var t model.Tag
+	query := `
+		UPDATE tags SET name = $1 WHERE id = $2 AND user_id = $3
+		RETURNING id, name, created_at, updated_at
+	`
+	err := db.DB.
+		QueryRow(query, name, id, userID).
+		Scan(&t.ID, &t.Name, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func DeleteTag(id, userID uuid.UUID) (bool, error) {
+	query := "DELETE FROM tags WHERE id = $1 AND user_id = $2"
+	result, err := db.DB.Exec(query, id, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+func CheckTagExists(id, userID uuid.UUID) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM tags WHERE id = $1 AND user_id = $2)"
+	err := db.DB.QueryRow(query, id, userID).Scan(&exists)
+	return exists, err
+}
+
+func AttachNoteTag(noteID, tagID uuid.UUID) error {
+	query := "INSERT INTO notes_tags (note_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := db.DB.Exec(query, noteID, tagID)
+	return err
+}
+
+func DetachNoteTag(noteID, tagID uuid.UUID) (bool, error) {
+	query := "DELETE FROM notes_tags WHERE note_id = $1 AND tag_id = $2"
+	result, err := db.DB.Exec(query, noteID, tagID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+// uniqueTagNames splits comma separated values and drops empty and case-insensitive duplicate
+// names so "all" matching can compare against the number of distinct names.
+func uniqueTagNames(values []string) []string {
+	seen := map[string]struct{}{}
+	result := []string{}
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			key := strings.ToLower(name)
+			if _, ok := seen[key]; ok || name == "" {
+				continue
+			}
+			seen[key] = struct{}{}
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/google/uuid"
+)
+
+func GetTrashedNotes(
+	userID uuid.UUID,
+	opts *model.TrashQuery,
+	retention time.Duration,
+) ([]model.TrashedNote, int, error) {
+	query := `
+		SELECT id, notebook_id, title, created_at, updated_at, deleted_at, deleted_at + $2::BIGINT * INTERVAL '1 second'
+		FROM notes
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := db.DB.Query(
+		query,
+		userID,
+		int64(retention.Seconds()),
+		opts.PageSize,
+		(opts.Page-1)*opts.PageSize,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	notes := []model.TrashedNote{}
+
+	defer rows.Close()
+	for rows.Next() {
+		var n model.TrashedNote
+		if err := rows.Scan(
+			&n.ID,
+			&n.NotebookID,
+			&n.Title,
+			&n.CreatedAt,
+			&n.UpdatedAt,
+			&n.DeletedAt,
+			&n.PurgeAt,
+		); err != nil {
+			log.Println(err)
+		}
+		notes = append(notes, n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	query = "SELECT COUNT(*) FROM notes WHERE user_id = $1 AND deleted_at IS NOT NULL"
+	if err = db.DB.QueryRow(query, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	return notes, total, nil
+}
+
+func RestoreNote(noteID, userID uuid.UUID) (bool, error) {
+	query := "UPDATE notes SET deleted_at = NULL WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL"
+	result, err := db.DB.Exec(query, noteID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+// DeleteTrashedNote permanently deletes a trashed note along with its members, invitations and
+// revisions.
+func DeleteTrashedNote(noteID, userID uuid.UUID) (bool, error) {
+	query := "DELETE FROM notes WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL"
+	result, err := db.DB.Exec(query, noteID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+func EmptyTrash(userID uuid.UUID) (int64, error) {
+	query := "DELETE FROM notes WHERE user_id = $1 AND deleted_at IS NOT NULL"
+	result, err := db.DB.Exec(query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func PurgeTrash(retention time.Duration) (int64, error) {
+	query := "DELETE FROM notes WHERE deleted_at < NOW() - $1::BIGINT * INTERVAL '1 second'"
+	result, err := db.DB.Exec(query, int64(retention.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// StartTrashPurger permanently deletes notes that have been in the trash longer than retention,
+// checking once per interval until the process exits.
+func StartTrashPurger(retention, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := PurgeTrash(retention)
+			if err != nil {
+				log.Println("Error purging trash:", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d trashed notes\n", purged)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/amiftachulh/notez-api/db"
+	"github.com/amiftachulh/notez-api/model"
+	"github.com/amiftachulh/notez-api/totp"
+	"github.com/google/uuid"
+)
+
+const (
+	recoveryCodeCount = 10
+	// loginChallengeMaxAttempts is the number of wrong codes after which a challenge is dropped
+	// and the user has to sign in with their password again.
+	loginChallengeMaxAttempts = 5
+)
+
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// SetPendingTOTPSecret stores a new secret for a user that hasn't enabled 2FA yet. It only
+// becomes active once a code generated from it is confirmed with ConfirmTOTP.
+func SetPendingTOTPSecret(userID uuid.UUID, secret string) (bool, error) {
+	query := "UPDATE users SET totp_secret = $1 WHERE id = $2 AND totp_enabled_at IS NULL"
+	result, err := db.DB.Exec(query, secret, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+// ConfirmTOTP enables 2FA when the code matches the pending secret and returns a fresh set of
+// recovery codes. It returns nil codes when there is no pending secret or the code is wrong.
+func ConfirmTOTP(userID uuid.UUID, code string) ([]string, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var secret *string
+	query := "SELECT totp_secret FROM users WHERE id = $1 AND totp_enabled_at IS NULL FOR UPDATE"
+	if err = tx.QueryRow(query, userID).Scan(&secret); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if secret == nil {
+		return nil, nil
+	}
+
+	step, ok := totp.Validate(*secret, code, time.Now())
+	if !ok {
+		return nil, nil
+	}
+
+	query = "UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $1 WHERE id = $2"
+	if _, err = tx.Exec(query, step, userID); err != nil {
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, tx.Commit()
+}
+
+// VerifyTOTP checks a code for a user with 2FA enabled. A code is accepted at most once so an
+// intercepted code can't be replayed within its validity window.
+func VerifyTOTP(userID uuid.UUID, code string) (bool, error) {
+	var secret string
+	query := "SELECT totp_secret FROM users WHERE id = $1 AND totp_enabled_at IS NOT NULL"
+	if err := db.DB.QueryRow(query, userID).Scan(&secret); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	query = `
+		UPDATE users SET totp_last_step = $1
+		WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)
+	`
+	result, err := db.DB.Exec(query, step, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+func DisableTOTP(userID uuid.UUID) (bool, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL
+		WHERE id = $1 AND totp_enabled_at IS NOT NULL
+	`
+	result, err := tx.Exec(query, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	query = "DELETE FROM totp_recovery_codes WHERE user_id = $1"
+	if _, err = tx.Exec(query, userID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// RegenerateRecoveryCodes invalidates the existing recovery codes and returns new ones. It
+// returns nil when the user doesn't have 2FA enabled.
+func RegenerateRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var enabled bool
+	query := "SELECT totp_enabled_at IS NOT NULL FROM users WHERE id = $1 FOR UPDATE"
+	if err = tx.QueryRow(query, userID).Scan(&enabled); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, tx.Commit()
+}
+
+// UseRecoveryCode marks a matching unused recovery code as used.
+func UseRecoveryCode(userID uuid.UUID, code string) (bool, error) {
+	query := `
+		UPDATE totp_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	result, err := db.DB.Exec(query, userID, HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID uuid.UUID) ([]string, error) {
+	query := "DELETE FROM totp_recovery_codes WHERE user_id = $1"
+	if _, err := tx.Exec(query, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	query = "INSERT INTO totp_recovery_codes (id, user_id, code_hash) VALUES ($1, $2, $3)"
+	for range recoveryCodeCount {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return nil, err
+		}
+		bytes := make([]byte, 10)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		code := recoveryEncoding.EncodeToString(bytes)
+		if _, err := tx.Exec(query, id, userID, HashToken(code)); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code[:8]+"-"+code[8:])
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode accepts codes typed with or without the separator and in any case.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// CreateLoginChallenge returns a token that stands in for the verified password until the
+// second factor is provided. remember carries the "remember me" choice over to the session.
+func CreateLoginChallenge(userID uuid.UUID, remember bool, expiresAt time.Time) (string, error) {
+	token, hash, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	query := "DELETE FROM login_challenges WHERE expires_at <= NOW()"
+	if _, err = db.DB.Exec(query); err != nil {
+		return "", err
+	}
+
+	query = "INSERT INTO login_challenges (token_hash, user_id, remember, expires_at) VALUES ($1, $2, $3, $4)"
+	if _, err = db.DB.Exec(query, hash, userID, remember, expiresAt); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetLoginChallenge returns a challenge that hasn't expired or run out of attempts.
+func GetLoginChallenge(token string) (*model.PendingLogin, error) {
+	var p model.PendingLogin
+	query := `
+		SELECT user_id, remember FROM login_challenges
+		WHERE token_hash = $1 AND expires_at > NOW() AND attempts < $2
+	`
+	err := db.DB.QueryRow(query, HashToken(token), loginChallengeMaxAttempts).Scan(&p.UserID, &p.Remember)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func RecordLoginChallengeFailure(token string) error {
+	query := "UPDATE login_challenges SET attempts = attempts + 1 WHERE token_hash = $1"
+	_, err := db.DB.Exec(query, HashToken(token))
+	return err
+}
+
+// DeleteLoginChallenge consumes the challenge. It returns false if it was already used.
+func DeleteLoginChallenge(token string) (bool, error) {
+	query := "DELETE FROM login_challenges WHERE token_hash = $1"
+	result, err := db.DB.Exec(query, HashToken(token))
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	return true, nil
+}
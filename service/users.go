@@ -17,14 +17,14 @@ func CheckEmailExists(email string) (bool, error) {
 	return exists, err
 }
 
-func CreateUser(email, password string) error {
+func CreateUser(email, password string) (uuid.UUID, error) {
 	id, err := uuid.NewV7()
 	if err != nil {
-		return err
+		return uuid.Nil, err
 	}
 	query := "INSERT INTO users (id, email, password) VALUES ($1, $2, $3)"
 	_, err = db.DB.Exec(query, id, email, password)
-	return err
+	return id, err
 }
 
 func GetUserByID(userID uuid.UUID) (*model.User, error) {
@@ -42,12 +42,27 @@ func GetUserByID(userID uuid.UUID) (*model.User, error) {
 	return &u, nil
 }
 
-func GetUserByEmail(email string) (*model.AuthUser, error) {
+const authUserColumns = `
+	id, name, email, email_verified_at, pending_email, totp_enabled_at IS NOT NULL,
+	disabled_at IS NOT NULL, password_reset_required, password, role, created_at, updated_at
+`
+
+func scanAuthUser(row *sql.Row) (*model.AuthUser, error) {
 	var u model.AuthUser
-	query := "SELECT id, name, email, password, role, created_at, updated_at FROM users WHERE email = $1"
-	if err := db.DB.
-		QueryRow(query, email).
-		Scan(&u.ID, &u.Name, &u.Email, &u.Password, &u.Role, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	if err := row.Scan(
+		&u.ID,
+		&u.Name,
+		&u.Email,
+		&u.EmailVerifiedAt,
+		&u.PendingEmail,
+		&u.TwoFactorEnabled,
+		&u.Disabled,
+		&u.PasswordResetRequired,
+		&u.Password,
+		&u.Role,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -56,6 +71,16 @@ func GetUserByEmail(email string) (*model.AuthUser, error) {
 	return &u, nil
 }
 
+func GetUserByEmail(email string) (*model.AuthUser, error) {
+	query := "SELECT " + authUserColumns + " FROM users WHERE email = $1"
+	return scanAuthUser(db.DB.QueryRow(query, email))
+}
+
+func GetAuthUserByID(userID uuid.UUID) (*model.AuthUser, error) {
+	query := "SELECT " + authUserColumns + " FROM users WHERE id = $1"
+	return scanAuthUser(db.DB.QueryRow(query, userID))
+}
+
 func GetUserIDByEmail(email string) (*uuid.UUID, error) {
 	var id uuid.UUID
 	query := "SELECT id FROM users WHERE email = $1"
@@ -84,8 +109,9 @@ func UpdateUserInfo(userID uuid.UUID, body *model.UpdateUserInfo) (bool, error)
 	return true, nil
 }
 
-func UpdateUserEmail(userID uuid.UUID, email string) (bool, error) {
-	query := "UPDATE users SET email = $1 WHERE id = $2"
+// SetPendingEmail stores the new address until it is confirmed with a verification token.
+func SetPendingEmail(userID uuid.UUID, email string) (bool, error) {
+	query := "UPDATE users SET pending_email = $1 WHERE id = $2"
 	result, err := db.DB.Exec(query, email, userID)
 	if err != nil {
 		return false, err
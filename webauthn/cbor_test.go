@@ -0,0 +1,180 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want interface{}
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1a000f4240", int64(1000000)},
+		{"1b000000e8d4a51000", int64(1000000000000)},
+		{"20", int64(-1)},
+		{"3903e7", int64(-1000)},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"6449455446", "IETF"},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"f93c00", float64(1)},
+		{"fa47c35000", float64(100000)},
+		{"fb3ff199999999999a", 1.1},
+		{"83010203", []interface{}{int64(1), int64(2), int64(3)}},
+		{"a201020304", map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{"a26161016162820203", map[interface{}]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{"c11a514b67b0", int64(1363896240)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			got, rest, err := decodeCBOR(append(data, 0xff))
+			if err != nil {
+				t.Fatalf("decodeCBOR: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+			if !bytes.Equal(rest, []byte{0xff}) {
+				t.Errorf("rest = %x, want ff", rest)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORRejects(t *testing.T) {
+	nested := bytes.Repeat([]byte{0x81}, maxCBORDepth+2)
+	nested = append(nested, 0x00)
+
+	tests := map[string]string{
+		"empty":                  "",
+		"indefinite length":      "5f4101ff",
+		"reserved info":          "1c",
+		"undefined simple value": "f0",
+		"byte string too long":   "5affffffff00",
+		"array too long":         "9bffffffffffffffff00",
+		"map too long":           "bbffffffffffffffff0000",
+		"integer overflow":       "1bffffffffffffffff",
+		"negative overflow":      "3bffffffffffffffff",
+		"array map key":          "a1810000",
+		"missing map value":      "a101",
+		"too deep":               hex.EncodeToString(nested),
+	}
+
+	for name, h := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, _ := hex.DecodeString(h)
+			if _, _, err := decodeCBOR(data); !errors.Is(err, errCBOR) {
+				t.Errorf("err = %v, want errCBOR", err)
+			}
+		})
+	}
+}
+
+// TestDecodeCBORTruncated cuts a real attestation object at every length. Every proper prefix
+// must fail cleanly instead of panicking or reading past the end.
+func TestDecodeCBORTruncated(t *testing.T) {
+	a := newTestAuthenticator(t)
+	challenge := newTestChallenge(t)
+	clientDataJSON, attestationObject := a.create(challenge)
+
+	for i := range len(attestationObject) {
+		if _, _, err := decodeCBOR(attestationObject[:i]); err == nil {
+			t.Errorf("prefix of %d bytes decoded", i)
+		}
+		if _, err := testRP.VerifyRegistration(challenge, clientDataJSON, attestationObject[:i]); err == nil {
+			t.Errorf("registration with %d byte prefix succeeded", i)
+		}
+	}
+
+	// Truncating the authenticator data inside a well-formed attestation object must fail too.
+	authData := a.authData(a.flags|flagAttestedCredData, true)
+	for i := range len(authData) {
+		truncated := encodeCBOR(cborMap{
+			{"fmt", "none"},
+			{"attStmt", cborMap{}},
+			{"authData", authData[:i]},
+		})
+		if _, err := testRP.VerifyRegistration(challenge, clientDataJSON, truncated); err == nil {
+			t.Errorf("registration with %d bytes of authenticator data succeeded", i)
+		}
+	}
+}
+
+func TestVerifySignatureMalformedKey(t *testing.T) {
+	a := newTestAuthenticator(t)
+	key := a.coseKey()
+
+	for i := range len(key) {
+		if err := verifySignature(key[:i], []byte("data"), []byte("sig")); err == nil {
+			t.Errorf("key prefix of %d bytes accepted", i)
+		}
+	}
+	if err := verifySignature(append(key, 0x00), []byte("data"), []byte("sig")); !errors.Is(err, errCBOR) {
+		t.Errorf("trailing data: err = %v, want errCBOR", err)
+	}
+
+	offCurve := encodeCBOR(cborMap{
+		{1, 2},
+		{3, AlgES256},
+		{-1, 1},
+		{-2, bytes.Repeat([]byte{1}, 32)},
+		{-3, bytes.Repeat([]byte{2}, 32)},
+	})
+	if err := checkPublicKey(offCurve); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("point off the curve: err = %v, want ErrUnsupportedKey", err)
+	}
+}
+
+func TestFloat16(t *testing.T) {
+	tests := map[uint16]float64{
+		0x0000: 0,
+		0x3c00: 1,
+		0xc000: -2,
+		0x7bff: 65504,
+		0x0001: 5.960464477539063e-8,
+		0x7c00: math.Inf(1),
+		0xfc00: math.Inf(-1),
+	}
+	for bits, want := range tests {
+		if got := float64(float16(bits)); got != want {
+			t.Errorf("float16(%#04x) = %v, want %v", bits, got, want)
+		}
+	}
+	if got := float16(0x7e00); !math.IsNaN(float64(got)) {
+		t.Errorf("float16(0x7e00) = %v, want NaN", got)
+	}
+}
+
+func FuzzDecodeCBOR(f *testing.F) {
+	a := newTestAuthenticator(f)
+	_, attestationObject := a.create("challenge")
+	f.Add(attestationObject)
+	f.Add(a.coseKey())
+	for _, h := range []string{"", "00", "a26161016162820203", "5affffffff00", "bbffffffffffffffff0000", "c1c1c100"} {
+		data, _ := hex.DecodeString(h)
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, rest, err := decodeCBOR(data)
+		if err == nil && !bytes.HasSuffix(data, rest) {
+			t.Fatalf("rest %x isn't a suffix of the input", rest)
+		}
+
+		// The same bytes as an attestation object or a public key must never panic either.
+		testRP.VerifyRegistration("challenge", []byte(`{"type":"webauthn.create","challenge":"challenge","origin":"`+testOrigin+`"}`), data)
+		verifySignature(data, []byte("data"), []byte("sig"))
+	})
+}
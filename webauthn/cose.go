@@ -0,0 +1,98 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// COSE algorithm identifiers offered during registration, in order of preference.
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+var ErrUnsupportedKey = errors.New("webauthn: unsupported public key")
+
+// verifySignature checks sig over data with a COSE_Key encoded public key.
+func verifySignature(coseKey, data, sig []byte) error {
+	decoded, rest, err := decodeCBOR(coseKey)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errCBOR
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return ErrUnsupportedKey
+	}
+
+	kty, _ := key[int64(1)].(int64)
+	alg, _ := key[int64(3)].(int64)
+	switch {
+	case kty == 2 && alg == AlgES256:
+		crv, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		y, _ := key[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return ErrUnsupportedKey
+		}
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return ErrUnsupportedKey
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		hash := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(pub, hash[:], sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case kty == 1 && alg == AlgEdDSA:
+		crv, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return ErrUnsupportedKey
+		}
+		if !ed25519.Verify(ed25519.PublicKey(x), data, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case kty == 3 && alg == AlgRS256:
+		n, _ := key[int64(-1)].([]byte)
+		e, _ := key[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return ErrUnsupportedKey
+		}
+		exponent := new(big.Int).SetBytes(e)
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		hash := sha256.Sum256(data)
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig); err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return ErrUnsupportedKey
+}
+
+// checkPublicKey reports whether the COSE_Key uses a supported algorithm and is well formed by
+// running it through verifySignature with an empty signature.
+func checkPublicKey(coseKey []byte) error {
+	if err := verifySignature(coseKey, nil, nil); !errors.Is(err, ErrInvalidSignature) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+// Package webauthn implements the server side of the WebAuthn registration and authentication
+// ceremonies for passkeys. Registration requests "none" attestation, so attestation statements
+// are not verified and credentials are trusted on first use.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagBackupEligible   = 0x08
+	flagBackedUp         = 0x10
+	flagAttestedCredData = 0x40
+
+	// Timeout is the ceremony timeout in milliseconds sent to the client.
+	Timeout = 5 * 60 * 1000
+)
+
+var (
+	ErrInvalidClientData = errors.New("webauthn: client data doesn't match the ceremony")
+	ErrInvalidAuthData   = errors.New("webauthn: malformed authenticator data")
+	ErrUserNotVerified   = errors.New("webauthn: user presence or verification missing")
+	ErrInvalidSignature  = errors.New("webauthn: invalid signature")
+	// ErrSignCount means the authenticator's counter went backwards, which suggests the
+	// credential was cloned.
+	ErrSignCount = errors.New("webauthn: sign count did not increase")
+)
+
+// RelyingParty identifies this server to authenticators. ID is the effective domain the
+// credentials are scoped to and Origins lists the frontends allowed to run ceremonies.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// Credential is a public key credential created during registration.
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte
+	SignCount      uint32
+	AAGUID         []byte
+	BackupEligible bool
+	BackedUp       bool
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions is the JSON form of PublicKeyCredentialCreationOptions accepted by
+// PublicKeyCredential.parseCreationOptionsFromJSON.
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions is the JSON form of PublicKeyCredentialRequestOptions accepted by
+// PublicKeyCredential.parseRequestOptionsFromJSON.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int                    `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// NewChallenge returns a random base64url encoded challenge.
+func NewChallenge() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// Descriptor returns the descriptor of a stored credential for allow and exclude lists.
+func Descriptor(credentialID []byte, transports []string) CredentialDescriptor {
+	return CredentialDescriptor{
+		Type:       "public-key",
+		ID:         base64.RawURLEncoding.EncodeToString(credentialID),
+		Transports: transports,
+	}
+}
+
+// CreationOptions builds the options for registering a discoverable credential. userHandle is
+// the opaque user ID stored on the authenticator and returned at login.
+func (rp RelyingParty) CreationOptions(challenge string, userHandle []byte, name, displayName string, exclude []CredentialDescriptor) CreationOptions {
+	return CreationOptions{
+		Challenge: challenge,
+		RP:        RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User: UserEntity{
+			ID:          base64.RawURLEncoding.EncodeToString(userHandle),
+			Name:        name,
+			DisplayName: displayName,
+		},
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            Timeout,
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions builds the options for an assertion. An empty allow list lets the user pick
+// any discoverable credential for this relying party.
+func (rp RelyingParty) RequestOptions(challenge string, allow []CredentialDescriptor) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		RPID:             rp.ID,
+		Timeout:          Timeout,
+		AllowCredentials: allow,
+		UserVerification: "required",
+	}
+}
+
+// Challenge extracts the challenge from client data so the server can look up the ceremony it
+// belongs to before verifying anything else.
+func Challenge(clientDataJSON []byte) (string, error) {
+	var cd clientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return "", ErrInvalidClientData
+	}
+	return cd.Challenge, nil
+}
+
+// VerifyRegistration verifies the response to navigator.credentials.create and returns the new
+// credential.
+func (rp RelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, err
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errCBOR
+	}
+	authData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, ErrInvalidAuthData
+	}
+
+	flags, signCount, err := rp.verifyAuthData(authData)
+	if err != nil {
+		return nil, err
+	}
+	if flags&flagAttestedCredData == 0 || len(authData) < 55 {
+		return nil, ErrInvalidAuthData
+	}
+
+	aaguid := authData[37:53]
+	idLength := int(binary.BigEndian.Uint16(authData[53:55]))
+	if idLength == 0 || idLength > 1023 || len(authData) < 55+idLength {
+		return nil, ErrInvalidAuthData
+	}
+	credentialID := authData[55 : 55+idLength]
+	keyData := authData[55+idLength:]
+	_, rest, err := decodeCBOR(keyData)
+	if err != nil {
+		return nil, err
+	}
+	keyData = keyData[:len(keyData)-len(rest)]
+	if err := checkPublicKey(keyData); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:             bytes.Clone(credentialID),
+		PublicKey:      bytes.Clone(keyData),
+		SignCount:      signCount,
+		AAGUID:         bytes.Clone(aaguid),
+		BackupEligible: flags&flagBackupEligible != 0,
+		BackedUp:       flags&flagBackedUp != 0,
+	}, nil
+}
+
+// VerifyAssertion verifies the response to navigator.credentials.get against the stored public
+// key and sign count and returns the new sign count to store.
+func (rp RelyingParty) VerifyAssertion(challenge string, clientDataJSON, authData, signature, publicKey []byte, storedSignCount uint32) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	_, signCount, err := rp.verifyAuthData(authData)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(bytes.Clone(authData), clientDataHash[:]...)
+	if err := verifySignature(publicKey, signed, signature); err != nil {
+		return 0, err
+	}
+
+	// Authenticators that don't implement counters, such as synced passkeys, always report 0.
+	if (signCount != 0 || storedSignCount != 0) && signCount <= storedSignCount {
+		return 0, ErrSignCount
+	}
+	return signCount, nil
+}
+
+func (rp RelyingParty) verifyClientData(clientDataJSON []byte, typ, challenge string) error {
+	var cd clientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return ErrInvalidClientData
+	}
+	if cd.Type != typ || cd.Challenge != challenge || cd.CrossOrigin {
+		return ErrInvalidClientData
+	}
+	if !slices.Contains(rp.Origins, cd.Origin) {
+		return ErrInvalidClientData
+	}
+	return nil
+}
+
+// verifyAuthData checks the fixed part of the authenticator data: the RP ID hash, user presence
+// and verification. It returns the flags and sign count.
+func (rp RelyingParty) verifyAuthData(authData []byte) (byte, uint32, error) {
+	if len(authData) < 37 {
+		return 0, 0, ErrInvalidAuthData
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData[:32], rpIDHash[:]) {
+		return 0, 0, ErrInvalidAuthData
+	}
+	flags := authData[32]
+	if flags&flagUserPresent == 0 || flags&flagUserVerified == 0 {
+		return 0, 0, ErrUserNotVerified
+	}
+	return flags, binary.BigEndian.Uint32(authData[33:37]), nil
+}
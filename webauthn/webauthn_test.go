@@ -0,0 +1,380 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const (
+	testRPID   = "notez.example.com"
+	testOrigin = "https://notez.example.com"
+)
+
+var testRP = RelyingParty{ID: testRPID, Name: "Notez", Origins: []string{testOrigin}}
+
+// cborPair keeps map entries in a fixed order so encoded test data is deterministic.
+type cborPair struct {
+	key, value interface{}
+}
+
+type cborMap []cborPair
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+}
+
+// encodeCBOR is the minimal encoder a software authenticator needs.
+func encodeCBOR(v interface{}) []byte {
+	switch v := v.(type) {
+	case int:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case cborMap:
+		out := cborHead(5, uint64(len(v)))
+		for _, pair := range v {
+			out = append(out, encodeCBOR(pair.key)...)
+			out = append(out, encodeCBOR(pair.value)...)
+		}
+		return out
+	}
+	panic("unsupported CBOR value")
+}
+
+// testAuthenticator is a software authenticator with a single ES256 credential. It produces
+// "none" attestation.
+type testAuthenticator struct {
+	t            testing.TB
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+	rpID         string
+	flags        byte
+	origin       string
+}
+
+func newTestAuthenticator(t testing.TB) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+	return &testAuthenticator{
+		t:            t,
+		key:          key,
+		credentialID: credentialID,
+		rpID:         testRPID,
+		flags:        flagUserPresent | flagUserVerified | flagBackupEligible,
+		origin:       testOrigin,
+	}
+}
+
+func (a *testAuthenticator) coseKey() []byte {
+	return encodeCBOR(cborMap{
+		{1, 2},
+		{3, AlgES256},
+		{-1, 1},
+		{-2, a.key.PublicKey.X.FillBytes(make([]byte, 32))},
+		{-3, a.key.PublicKey.Y.FillBytes(make([]byte, 32))},
+	})
+}
+
+func (a *testAuthenticator) authData(flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+func (a *testAuthenticator) clientData(typ, challenge string) []byte {
+	data, err := json.Marshal(clientData{Type: typ, Challenge: challenge, Origin: a.origin})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return data
+}
+
+// create returns the client data and attestation object of navigator.credentials.create.
+func (a *testAuthenticator) create(challenge string) ([]byte, []byte) {
+	attestationObject := encodeCBOR(cborMap{
+		{"fmt", "none"},
+		{"attStmt", cborMap{}},
+		{"authData", a.authData(a.flags|flagAttestedCredData, true)},
+	})
+	return a.clientData("webauthn.create", challenge), attestationObject
+}
+
+// get returns the client data, authenticator data and signature of navigator.credentials.get.
+func (a *testAuthenticator) get(challenge string) ([]byte, []byte, []byte) {
+	clientDataJSON := a.clientData("webauthn.get", challenge)
+	authData := a.authData(a.flags, false)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	hash := sha256.Sum256(append(bytes.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, hash[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return clientDataJSON, authData, signature
+}
+
+func newTestChallenge(t *testing.T) string {
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challenge
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	a := newTestAuthenticator(t)
+
+	challenge := newTestChallenge(t)
+	clientDataJSON, attestationObject := a.create(challenge)
+	got, err := Challenge(clientDataJSON)
+	if err != nil || got != challenge {
+		t.Fatalf("Challenge = %q, %v, want %q", got, err, challenge)
+	}
+
+	credential, err := testRP.VerifyRegistration(challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	if !bytes.Equal(credential.ID, a.credentialID) {
+		t.Errorf("credential ID = %x, want %x", credential.ID, a.credentialID)
+	}
+	if !bytes.Equal(credential.PublicKey, a.coseKey()) {
+		t.Error("public key doesn't match the attested COSE key")
+	}
+	if len(credential.AAGUID) != 16 || credential.SignCount != 0 || !credential.BackupEligible || credential.BackedUp {
+		t.Errorf("unexpected credential %+v", credential)
+	}
+
+	for i := uint32(1); i <= 2; i++ {
+		a.signCount = i
+		challenge = newTestChallenge(t)
+		clientDataJSON, authData, signature := a.get(challenge)
+
+		signCount, err := testRP.VerifyAssertion(challenge, clientDataJSON, authData, signature, credential.PublicKey, i-1)
+		if err != nil {
+			t.Fatalf("VerifyAssertion %d: %v", i, err)
+		}
+		if signCount != i {
+			t.Errorf("sign count = %d, want %d", signCount, i)
+		}
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(a *testAuthenticator)
+		want   error
+	}{
+		{"wrong origin", func(a *testAuthenticator) { a.origin = "https://evil.example.com" }, ErrInvalidClientData},
+		{"wrong rpIdHash", func(a *testAuthenticator) { a.rpID = "evil.example.com" }, ErrInvalidAuthData},
+		{"missing UP flag", func(a *testAuthenticator) { a.flags &^= flagUserPresent }, ErrUserNotVerified},
+		{"missing UV flag", func(a *testAuthenticator) { a.flags &^= flagUserVerified }, ErrUserNotVerified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthenticator(t)
+			tt.modify(a)
+			challenge := newTestChallenge(t)
+			clientDataJSON, attestationObject := a.create(challenge)
+
+			if _, err := testRP.VerifyRegistration(challenge, clientDataJSON, attestationObject); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("wrong challenge", func(t *testing.T) {
+		a := newTestAuthenticator(t)
+		clientDataJSON, attestationObject := a.create(newTestChallenge(t))
+
+		if _, err := testRP.VerifyRegistration(newTestChallenge(t), clientDataJSON, attestationObject); !errors.Is(err, ErrInvalidClientData) {
+			t.Errorf("err = %v, want ErrInvalidClientData", err)
+		}
+	})
+
+	t.Run("assertion client data", func(t *testing.T) {
+		a := newTestAuthenticator(t)
+		challenge := newTestChallenge(t)
+		_, attestationObject := a.create(challenge)
+
+		clientDataJSON := a.clientData("webauthn.get", challenge)
+		if _, err := testRP.VerifyRegistration(challenge, clientDataJSON, attestationObject); !errors.Is(err, ErrInvalidClientData) {
+			t.Errorf("err = %v, want ErrInvalidClientData", err)
+		}
+	})
+
+	t.Run("cross origin", func(t *testing.T) {
+		a := newTestAuthenticator(t)
+		challenge := newTestChallenge(t)
+		_, attestationObject := a.create(challenge)
+
+		clientDataJSON, _ := json.Marshal(clientData{
+			Type:        "webauthn.create",
+			Challenge:   challenge,
+			Origin:      testOrigin,
+			CrossOrigin: true,
+		})
+		if _, err := testRP.VerifyRegistration(challenge, clientDataJSON, attestationObject); !errors.Is(err, ErrInvalidClientData) {
+			t.Errorf("err = %v, want ErrInvalidClientData", err)
+		}
+	})
+
+	t.Run("missing attested credential data", func(t *testing.T) {
+		a := newTestAuthenticator(t)
+		challenge := newTestChallenge(t)
+		clientDataJSON := a.clientData("webauthn.create", challenge)
+		attestationObject := encodeCBOR(cborMap{
+			{"fmt", "none"},
+			{"attStmt", cborMap{}},
+			{"authData", a.authData(a.flags, false)},
+		})
+
+		if _, err := testRP.VerifyRegistration(challenge, clientDataJSON, attestationObject); !errors.Is(err, ErrInvalidAuthData) {
+			t.Errorf("err = %v, want ErrInvalidAuthData", err)
+		}
+	})
+
+	t.Run("unsupported key", func(t *testing.T) {
+		a := newTestAuthenticator(t)
+		challenge := newTestChallenge(t)
+		clientDataJSON := a.clientData("webauthn.create", challenge)
+
+		authData := a.authData(a.flags|flagAttestedCredData, false)
+		authData = append(authData, make([]byte, 16)...)
+		authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+		authData = append(authData, a.credentialID...)
+		authData = append(authData, encodeCBOR(cborMap{{1, 2}, {3, -35}})...)
+		attestationObject := encodeCBOR(cborMap{{"fmt", "none"}, {"attStmt", cborMap{}}, {"authData", authData}})
+
+		if _, err := testRP.VerifyRegistration(challenge, clientDataJSON, attestationObject); !errors.Is(err, ErrUnsupportedKey) {
+			t.Errorf("err = %v, want ErrUnsupportedKey", err)
+		}
+	})
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(a *testAuthenticator)
+		want   error
+	}{
+		{"wrong origin", func(a *testAuthenticator) { a.origin = "https://evil.example.com" }, ErrInvalidClientData},
+		{"wrong rpIdHash", func(a *testAuthenticator) { a.rpID = "evil.example.com" }, ErrInvalidAuthData},
+		{"missing UP flag", func(a *testAuthenticator) { a.flags &^= flagUserPresent }, ErrUserNotVerified},
+		{"missing UV flag", func(a *testAuthenticator) { a.flags &^= flagUserVerified }, ErrUserNotVerified},
+		{"other key", func(a *testAuthenticator) {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				a.t.Fatal(err)
+			}
+			a.key = key
+		}, ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthenticator(t)
+			publicKey := a.coseKey()
+			a.signCount = 5
+			tt.modify(a)
+
+			challenge := newTestChallenge(t)
+			clientDataJSON, authData, signature := a.get(challenge)
+			if _, err := testRP.VerifyAssertion(challenge, clientDataJSON, authData, signature, publicKey, 4); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("tampered authenticator data", func(t *testing.T) {
+		a := newTestAuthenticator(t)
+		a.signCount = 5
+		challenge := newTestChallenge(t)
+		clientDataJSON, authData, signature := a.get(challenge)
+		authData[36]++
+
+		if _, err := testRP.VerifyAssertion(challenge, clientDataJSON, authData, signature, a.coseKey(), 4); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("err = %v, want ErrInvalidSignature", err)
+		}
+	})
+
+	t.Run("registration client data", func(t *testing.T) {
+		a := newTestAuthenticator(t)
+		challenge := newTestChallenge(t)
+		_, authData, signature := a.get(challenge)
+		clientDataJSON := a.clientData("webauthn.create", challenge)
+
+		if _, err := testRP.VerifyAssertion(challenge, clientDataJSON, authData, signature, a.coseKey(), 0); !errors.Is(err, ErrInvalidClientData) {
+			t.Errorf("err = %v, want ErrInvalidClientData", err)
+		}
+	})
+}
+
+func TestVerifyAssertionSignCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		count  uint32
+		stored uint32
+		want   error
+	}{
+		{"increased", 8, 7, nil},
+		{"regressed", 6, 7, ErrSignCount},
+		{"repeated", 7, 7, ErrSignCount},
+		{"reset to zero", 0, 7, ErrSignCount},
+		{"not implemented", 0, 0, nil},
+		{"first use", 1, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthenticator(t)
+			a.signCount = tt.count
+			challenge := newTestChallenge(t)
+			clientDataJSON, authData, signature := a.get(challenge)
+
+			signCount, err := testRP.VerifyAssertion(challenge, clientDataJSON, authData, signature, a.coseKey(), tt.stored)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if err == nil && signCount != tt.count {
+				t.Errorf("sign count = %d, want %d", signCount, tt.count)
+			}
+		})
+	}
+}